@@ -0,0 +1,196 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/utkuozdemir/pv-migrate/archive"
+	"github.com/utkuozdemir/pv-migrate/migrator"
+	"github.com/utkuozdemir/pv-migrate/rsync/progress"
+)
+
+const (
+	CommandArchive = "archive"
+	CommandRestore = "restore"
+
+	FlagOutput      = "output"
+	FlagInput       = "input"
+	FlagCompression = "compression"
+)
+
+func buildArchiveCmd(ctx context.Context) *cobra.Command {
+	cmd := cobra.Command{
+		Use: fmt.Sprintf("%s [--%s=<source-ns>] --%s=<source-pvc> --%s=<file>",
+			CommandArchive, FlagSourceNamespace, FlagSource, FlagOutput),
+		Short: "Archive the data in a Kubernetes PersistentVolumeClaim into a local tarball",
+		Long: "Archive the data in a Kubernetes PersistentVolumeClaim into a local tarball.\n\n" +
+			"Runs a helper pod which mounts the PVC and streams its data as a tar archive over the exec API. " +
+			"The archive contains a manifest with the spec of the source PVC and a checksum of the data.",
+		Args: cobra.NoArgs,
+		RunE: runArchive,
+	}
+
+	flags := cmd.Flags()
+
+	flags.StringP(FlagSourceKubeconfig, "k", "", "path of the kubeconfig file of the source PVC")
+	flags.StringP(FlagSourceContext, "c", "", "context in the kubeconfig file of the source PVC")
+	flags.StringP(FlagSourceNamespace, "n", "", "namespace of the source PVC")
+	flags.String(FlagSource, "", "source PVC name")
+	flags.StringP(FlagSourcePath, "p", "/", "the filesystem path to archive in the source PVC")
+	flags.StringP(FlagOutput, "o", "", "the archive file to write")
+	flags.String(FlagCompression, archive.CompressionAuto, "compression of the archive. Valid values are "+
+		strings.Join(archive.Compressions, ",")+" ('auto' picks it by the extension of the output file)")
+	flags.BoolP(FlagSourceMountReadOnly, "R", true, "mount the source PVC in ReadOnly mode")
+
+	cmd.MarkFlagRequired(FlagSource) //nolint:errcheck
+	cmd.MarkFlagRequired(FlagOutput) //nolint:errcheck
+
+	setArchiveCmdFlags(&cmd)
+
+	//nolint:errcheck
+	{
+		cmd.RegisterFlagCompletionFunc(FlagSourceContext, buildKubeContextCompletionFunc(FlagSourceKubeconfig))
+		cmd.RegisterFlagCompletionFunc(FlagSourceNamespace,
+			buildKubeNSCompletionFunc(ctx, FlagSourceKubeconfig, FlagSourceContext))
+		cmd.RegisterFlagCompletionFunc(FlagSourcePath, completionFuncNoFileComplete)
+		cmd.RegisterFlagCompletionFunc(FlagSource, buildPVCCompletionFunc(ctx, false))
+		cmd.RegisterFlagCompletionFunc(FlagCompression, buildStaticSliceCompletionFunc(archive.Compressions))
+	}
+
+	return &cmd
+}
+
+func buildRestoreCmd(ctx context.Context) *cobra.Command {
+	cmd := cobra.Command{
+		Use: fmt.Sprintf("%s --%s=<file> [--%s=<dest-ns>] --%s=<dest-pvc>",
+			CommandRestore, FlagInput, FlagDestNamespace, FlagDest),
+		Short: "Restore the data in a local tarball into a Kubernetes PersistentVolumeClaim",
+		Long: "Restore the data in a local tarball created by the archive command " +
+			"into a Kubernetes PersistentVolumeClaim.\n\n" +
+			"The checksum of the archive is verified before any data is written into the PVC.",
+		Args: cobra.NoArgs,
+		RunE: runRestore,
+	}
+
+	flags := cmd.Flags()
+
+	flags.StringP(FlagInput, "i", "", "the archive file to restore")
+	flags.StringP(FlagDestKubeconfig, "K", "", "path of the kubeconfig file of the destination PVC")
+	flags.StringP(FlagDestContext, "C", "", "context in the kubeconfig file of the destination PVC")
+	flags.StringP(FlagDestNamespace, "N", "", "namespace of the destination PVC")
+	flags.String(FlagDest, "", "destination PVC name")
+	flags.StringP(FlagDestPath, "P", "/", "the filesystem path to restore into in the destination PVC")
+
+	cmd.MarkFlagRequired(FlagInput) //nolint:errcheck
+	cmd.MarkFlagRequired(FlagDest)  //nolint:errcheck
+
+	setArchiveCmdFlags(&cmd)
+
+	//nolint:errcheck
+	{
+		cmd.RegisterFlagCompletionFunc(FlagDestContext, buildKubeContextCompletionFunc(FlagDestKubeconfig))
+		cmd.RegisterFlagCompletionFunc(FlagDestNamespace,
+			buildKubeNSCompletionFunc(ctx, FlagDestKubeconfig, FlagDestContext))
+		cmd.RegisterFlagCompletionFunc(FlagDestPath, completionFuncNoFileComplete)
+		cmd.RegisterFlagCompletionFunc(FlagDest, buildPVCCompletionFunc(ctx, true))
+	}
+
+	return &cmd
+}
+
+// setArchiveCmdFlags sets the flags shared by the archive and restore commands.
+func setArchiveCmdFlags(cmd *cobra.Command) {
+	flags := cmd.Flags()
+
+	flags.Bool(FlagIgnoreMounted, false, "do not fail if the PVC is mounted")
+	flags.Bool(FlagNoChown, false, "do not restore the ownership of the files")
+	flags.BoolP(FlagSkipCleanup, "x", false, "skip cleanup of the helper pod")
+	flags.BoolP(FlagNoProgressBar, "b", false, "do not display a progress bar")
+
+	flags.DurationP(FlagHelmTimeout, "t", 1*time.Minute, "install/uninstall timeout for helm releases")
+	flags.StringSliceP(FlagHelmValues, "f", nil,
+		"set additional Helm values by a YAML file or a URL (can specify multiple)")
+	flags.StringSlice(FlagHelmSet, nil, "set additional Helm values on the command line (can specify "+
+		"multiple or separate values with commas: key1=val1,key2=val2)")
+	flags.StringSlice(FlagHelmSetString, nil, "set additional Helm STRING values on the command line "+
+		"(can specify multiple or separate values with commas: key1=val1,key2=val2)")
+	flags.StringSlice(FlagHelmSetFile, nil, "set additional Helm values from respective files specified "+
+		"via the command line (can specify multiple or separate values with commas: key1=path1,key2=path2)")
+
+	//nolint:errcheck
+	{
+		cmd.RegisterFlagCompletionFunc(FlagHelmSet, completionFuncNoFileComplete)
+		cmd.RegisterFlagCompletionFunc(FlagHelmSetString, completionFuncNoFileComplete)
+		cmd.RegisterFlagCompletionFunc(FlagHelmSetFile, completionFuncNoFileComplete)
+	}
+}
+
+func runArchive(cmd *cobra.Command, _ []string) error {
+	flags := cmd.Flags()
+	ctx := cmd.Context()
+
+	logger, canDisplayProgressBar, err := buildLogger(flags)
+	if err != nil {
+		return fmt.Errorf("failed to build logger: %w", err)
+	}
+
+	if canDisplayProgressBar {
+		ctx = context.WithValue(ctx, progress.CanDisplayProgressBarContextKey{}, struct{}{})
+	}
+
+	src, _ := flags.GetString(FlagSource)
+	output, _ := flags.GetString(FlagOutput)
+	compression, _ := flags.GetString(FlagCompression)
+
+	// validated before the migrator installs the helper pod and creates the output file
+	if !slices.Contains(archive.Compressions, compression) {
+		return fmt.Errorf("invalid --%s %q, valid values are %s",
+			FlagCompression, compression, strings.Join(archive.Compressions, ","))
+	}
+
+	if compression == archive.CompressionAuto {
+		compression = archive.CompressionForFile(output)
+	}
+
+	request := buildRequest(flags, buildSrcPVCInfo(flags, src), nil)
+
+	logger.Info("🚀 Starting archive")
+
+	if err = migrator.New().Archive(ctx, request, output, compression, logger); err != nil {
+		return fmt.Errorf("archive failed: %w", err)
+	}
+
+	return nil
+}
+
+func runRestore(cmd *cobra.Command, _ []string) error {
+	flags := cmd.Flags()
+	ctx := cmd.Context()
+
+	logger, canDisplayProgressBar, err := buildLogger(flags)
+	if err != nil {
+		return fmt.Errorf("failed to build logger: %w", err)
+	}
+
+	if canDisplayProgressBar {
+		ctx = context.WithValue(ctx, progress.CanDisplayProgressBarContextKey{}, struct{}{})
+	}
+
+	input, _ := flags.GetString(FlagInput)
+	dest, _ := flags.GetString(FlagDest)
+
+	request := buildRequest(flags, nil, buildDestPVCInfo(flags, dest))
+
+	logger.Info("🚀 Starting restore")
+
+	if err = migrator.New().Restore(ctx, request, input, logger); err != nil {
+		return fmt.Errorf("restore failed: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,213 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/utkuozdemir/pv-migrate/migrator"
+	"github.com/utkuozdemir/pv-migrate/rsync/progress"
+	"github.com/utkuozdemir/pv-migrate/ssh"
+)
+
+const (
+	CommandExport = "export"
+	CommandImport = "import"
+
+	FlagTo   = "to"
+	FlagFrom = "from"
+)
+
+func buildExportCmd(ctx context.Context) *cobra.Command {
+	cmd := cobra.Command{
+		Use: fmt.Sprintf("%s [--%s=<source-ns>] --%s=<source-pvc> --%s=<local-dir>",
+			CommandExport, FlagSourceNamespace, FlagSource, FlagTo),
+		Short: "Export the data in a Kubernetes PersistentVolumeClaim into a local directory",
+		Long: "Export the data in a Kubernetes PersistentVolumeClaim into a local directory.\n\n" +
+			"Runs an sshd pod which mounts the PVC, port-forwards to it and runs rsync on this machine. " +
+			"Requires rsync and ssh binaries to be available locally.",
+		Args: cobra.NoArgs,
+		RunE: runExport,
+	}
+
+	flags := cmd.Flags()
+
+	flags.StringP(FlagSourceKubeconfig, "k", "", "path of the kubeconfig file of the source PVC")
+	flags.StringP(FlagSourceContext, "c", "", "context in the kubeconfig file of the source PVC")
+	flags.StringP(FlagSourceNamespace, "n", "", "namespace of the source PVC")
+	flags.String(FlagSource, "", "source PVC name")
+	flags.StringP(FlagSourcePath, "p", "/", "the filesystem path to export in the source PVC")
+	flags.String(FlagTo, "", "the local directory to export the data into")
+	flags.BoolP(FlagSourceMountReadOnly, "R", true, "mount the source PVC in ReadOnly mode")
+
+	cmd.MarkFlagRequired(FlagSource) //nolint:errcheck
+	cmd.MarkFlagRequired(FlagTo)     //nolint:errcheck
+	cmd.MarkFlagDirname(FlagTo)      //nolint:errcheck
+
+	setLocalDirCmdFlags(&cmd)
+
+	//nolint:errcheck
+	{
+		cmd.RegisterFlagCompletionFunc(FlagSourceContext, buildKubeContextCompletionFunc(FlagSourceKubeconfig))
+		cmd.RegisterFlagCompletionFunc(FlagSourceNamespace,
+			buildKubeNSCompletionFunc(ctx, FlagSourceKubeconfig, FlagSourceContext))
+		cmd.RegisterFlagCompletionFunc(FlagSourcePath, completionFuncNoFileComplete)
+		cmd.RegisterFlagCompletionFunc(FlagSource, buildPVCCompletionFunc(ctx, false))
+	}
+
+	return &cmd
+}
+
+func buildImportCmd(ctx context.Context) *cobra.Command {
+	cmd := cobra.Command{
+		Use: fmt.Sprintf("%s --%s=<local-dir> [--%s=<dest-ns>] --%s=<dest-pvc>",
+			CommandImport, FlagFrom, FlagDestNamespace, FlagDest),
+		Short: "Import the data in a local directory into a Kubernetes PersistentVolumeClaim",
+		Long: "Import the data in a local directory into a Kubernetes PersistentVolumeClaim.\n\n" +
+			"Runs an sshd pod which mounts the PVC, port-forwards to it and runs rsync on this machine. " +
+			"Requires rsync and ssh binaries to be available locally.",
+		Args: cobra.NoArgs,
+		RunE: runImport,
+	}
+
+	flags := cmd.Flags()
+
+	flags.String(FlagFrom, "", "the local directory to import the data from")
+	flags.StringP(FlagDestKubeconfig, "K", "", "path of the kubeconfig file of the destination PVC")
+	flags.StringP(FlagDestContext, "C", "", "context in the kubeconfig file of the destination PVC")
+	flags.StringP(FlagDestNamespace, "N", "", "namespace of the destination PVC")
+	flags.String(FlagDest, "", "destination PVC name")
+	flags.StringP(FlagDestPath, "P", "/", "the filesystem path to import into in the destination PVC")
+
+	cmd.MarkFlagRequired(FlagFrom) //nolint:errcheck
+	cmd.MarkFlagRequired(FlagDest) //nolint:errcheck
+	cmd.MarkFlagDirname(FlagFrom)  //nolint:errcheck
+
+	setLocalDirCmdFlags(&cmd)
+
+	//nolint:errcheck
+	{
+		cmd.RegisterFlagCompletionFunc(FlagDestContext, buildKubeContextCompletionFunc(FlagDestKubeconfig))
+		cmd.RegisterFlagCompletionFunc(FlagDestNamespace,
+			buildKubeNSCompletionFunc(ctx, FlagDestKubeconfig, FlagDestContext))
+		cmd.RegisterFlagCompletionFunc(FlagDestPath, completionFuncNoFileComplete)
+		cmd.RegisterFlagCompletionFunc(FlagDest, buildPVCCompletionFunc(ctx, true))
+	}
+
+	return &cmd
+}
+
+// setLocalDirCmdFlags sets the flags shared by the export and import commands.
+func setLocalDirCmdFlags(cmd *cobra.Command) {
+	flags := cmd.Flags()
+
+	flags.BoolP(FlagDestDeleteExtraneousFiles, "d", false,
+		"delete extraneous files on the destination by using rsync's '--delete' flag")
+	flags.BoolP(FlagIgnoreMounted, "i", false, "do not fail if the PVC is mounted")
+	flags.BoolP(FlagNoChown, "o", false, "omit chown on rsync")
+	flags.BoolP(FlagSkipCleanup, "x", false, "skip cleanup of the transfer")
+	flags.BoolP(FlagNoProgressBar, "b", false, "do not display a progress bar")
+	flags.StringP(FlagSSHKeyAlgorithm, "a", ssh.Ed25519KeyAlgorithm,
+		"ssh key algorithm to be used. Valid values are "+strings.Join(ssh.KeyAlgorithms, ","))
+	flags.Bool(FlagCompress, true, "compress data during transfer ('-z' flag of rsync)")
+	setRootlessFlags(flags)
+
+	flags.DurationP(FlagHelmTimeout, "t", 1*time.Minute, "install/uninstall timeout for helm releases")
+	flags.StringSliceP(FlagHelmValues, "f", nil,
+		"set additional Helm values by a YAML file or a URL (can specify multiple)")
+	flags.StringSlice(FlagHelmSet, nil, "set additional Helm values on the command line (can specify "+
+		"multiple or separate values with commas: key1=val1,key2=val2)")
+	flags.StringSlice(FlagHelmSetString, nil, "set additional Helm STRING values on the command line "+
+		"(can specify multiple or separate values with commas: key1=val1,key2=val2)")
+	flags.StringSlice(FlagHelmSetFile, nil, "set additional Helm values from respective files specified "+
+		"via the command line (can specify multiple or separate values with commas: key1=path1,key2=path2)")
+
+	//nolint:errcheck
+	{
+		cmd.RegisterFlagCompletionFunc(FlagSSHKeyAlgorithm, buildStaticSliceCompletionFunc(ssh.KeyAlgorithms))
+		cmd.RegisterFlagCompletionFunc(FlagRootlessUID, completionFuncNoFileComplete)
+		cmd.RegisterFlagCompletionFunc(FlagHelmSet, completionFuncNoFileComplete)
+		cmd.RegisterFlagCompletionFunc(FlagHelmSetString, completionFuncNoFileComplete)
+		cmd.RegisterFlagCompletionFunc(FlagHelmSetFile, completionFuncNoFileComplete)
+	}
+}
+
+func runExport(cmd *cobra.Command, _ []string) error {
+	flags := cmd.Flags()
+	ctx := cmd.Context()
+
+	logger, canDisplayProgressBar, err := buildLogger(flags)
+	if err != nil {
+		return fmt.Errorf("failed to build logger: %w", err)
+	}
+
+	if canDisplayProgressBar {
+		ctx = context.WithValue(ctx, progress.CanDisplayProgressBarContextKey{}, struct{}{})
+	}
+
+	src, _ := flags.GetString(FlagSource)
+	localDir, _ := flags.GetString(FlagTo)
+
+	request := buildRequest(flags, buildSrcPVCInfo(flags, src), nil)
+
+	if err = validateRootless(request); err != nil {
+		return err
+	}
+
+	logger.Info("🚀 Starting export")
+
+	if err = migrator.New().Export(ctx, request, localDir, logger); err != nil {
+		return fmt.Errorf("export failed: %w", err)
+	}
+
+	return nil
+}
+
+func runImport(cmd *cobra.Command, _ []string) error {
+	flags := cmd.Flags()
+	ctx := cmd.Context()
+
+	logger, canDisplayProgressBar, err := buildLogger(flags)
+	if err != nil {
+		return fmt.Errorf("failed to build logger: %w", err)
+	}
+
+	if canDisplayProgressBar {
+		ctx = context.WithValue(ctx, progress.CanDisplayProgressBarContextKey{}, struct{}{})
+	}
+
+	localDir, _ := flags.GetString(FlagFrom)
+	dest, _ := flags.GetString(FlagDest)
+
+	info, err := os.Stat(localDir)
+	if err != nil {
+		return fmt.Errorf("failed to stat local directory: %w", err)
+	}
+
+	if !info.IsDir() {
+		return errors.New("not a directory: " + localDir)
+	}
+
+	request := buildRequest(flags, nil, buildDestPVCInfo(flags, dest))
+
+	if err = validateRootless(request); err != nil {
+		return err
+	}
+
+	logger.Info("🚀 Starting import")
+
+	if request.DeleteExtraneousFiles {
+		logger.Info("❕ Extraneous files will be deleted from the destination")
+	}
+
+	if err = migrator.New().Import(ctx, request, localDir, logger); err != nil {
+		return fmt.Errorf("import failed: %w", err)
+	}
+
+	return nil
+}
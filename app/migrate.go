@@ -12,12 +12,16 @@ import (
 	"github.com/mattn/go-isatty"
 	"github.com/spf13/cobra"
 	flag "github.com/spf13/pflag"
+	corev1 "k8s.io/api/core/v1"
 
+	"github.com/utkuozdemir/pv-migrate/k8s"
 	"github.com/utkuozdemir/pv-migrate/migration"
 	"github.com/utkuozdemir/pv-migrate/migrator"
+	"github.com/utkuozdemir/pv-migrate/rsync"
 	"github.com/utkuozdemir/pv-migrate/rsync/progress"
 	"github.com/utkuozdemir/pv-migrate/ssh"
 	"github.com/utkuozdemir/pv-migrate/strategy"
+	"github.com/utkuozdemir/pv-migrate/transfer"
 )
 
 const (
@@ -43,6 +47,38 @@ const (
 	FlagDestHostOverride = "dest-host-override"
 	FlagLBSvcTimeout     = "lbsvc-timeout"
 
+	FlagNodePortAddressType = "nodeport-address-type"
+
+	FlagFidelity = "fidelity"
+	FlagPreserve = "preserve"
+
+	FlagLargeFileMode = "large-file-mode"
+
+	FlagChown            = "chown"
+	FlagUserMap          = "usermap"
+	FlagGroupMap         = "groupmap"
+	FlagMatchDestFSGroup = "match-dest-fsgroup"
+
+	FlagParallel           = "parallel"
+	FlagAllowVanishedFiles = "allow-vanished-files"
+	FlagChangesLog         = "changes-log"
+
+	FlagRsyncRetries      = "rsync-retries"
+	FlagRsyncRetryBackoff = "rsync-retry-backoff"
+
+	FlagBwLimit = "bwlimit"
+	FlagWindow  = "window"
+
+	FlagInclude    = "include"
+	FlagExclude    = "exclude"
+	FlagFilterFile = "filter-file"
+
+	FlagRootless    = "rootless"
+	FlagRootlessUID = "rootless-uid"
+
+	FlagRelaySSH    = "relay-ssh"
+	FlagRelaySSHKey = "relay-ssh-key"
+
 	FlagDestDeleteExtraneousFiles = "dest-delete-extraneous-files"
 	FlagIgnoreMounted             = "ignore-mounted"
 	FlagNoChown                   = "no-chown"
@@ -52,6 +88,7 @@ const (
 	FlagStrategies                = "strategies"
 	FlagSSHKeyAlgorithm           = "ssh-key-algorithm"
 	FlagCompress                  = "compress"
+	FlagEngine                    = "engine"
 
 	FlagHelmTimeout   = "helm-timeout"
 	FlagHelmValues    = "helm-values"
@@ -60,6 +97,11 @@ const (
 	FlagHelmSetFile   = "helm-set-file"
 
 	lbSvcTimeoutDefault = 2 * time.Minute
+
+	rsyncRetriesDefault      = 10
+	rsyncRetryBackoffDefault = 5 * time.Second
+
+	rootlessUIDDefault = 1000
 )
 
 var completionFuncNoFileComplete = func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
@@ -124,6 +166,10 @@ func BuildMigrateCmd(ctx context.Context, version, commit, date string, legacy b
 
 	cmd.AddCommand(buildCompletionCmd())
 
+	if !legacy {
+		cmd.AddCommand(buildExportCmd(ctx), buildImportCmd(ctx), buildArchiveCmd(ctx), buildRestoreCmd(ctx))
+	}
+
 	return &cmd
 }
 
@@ -144,8 +190,34 @@ func setMigrateCmdCompletion(ctx context.Context, cmd *cobra.Command, levels, fo
 		buildKubeNSCompletionFunc(ctx, FlagDestKubeconfig, FlagDestContext))
 	cmd.RegisterFlagCompletionFunc(FlagDestPath, completionFuncNoFileComplete)
 
-	cmd.RegisterFlagCompletionFunc(FlagStrategies, buildSliceCompletionFunc(strategy.AllStrategies))
+	cmd.RegisterFlagCompletionFunc(FlagStrategies, buildSliceCompletionFunc(
+		append([]string{strategy.AutoStrategy}, strategy.AllStrategies...)))
 	cmd.RegisterFlagCompletionFunc(FlagSSHKeyAlgorithm, buildStaticSliceCompletionFunc(ssh.KeyAlgorithms))
+	cmd.RegisterFlagCompletionFunc(FlagNodePortAddressType, buildStaticSliceCompletionFunc(k8s.NodeAddressTypes))
+	cmd.RegisterFlagCompletionFunc(FlagEngine, buildStaticSliceCompletionFunc(transfer.Engines))
+	cmd.RegisterFlagCompletionFunc(FlagFidelity, buildStaticSliceCompletionFunc(rsync.Fidelities))
+	cmd.RegisterFlagCompletionFunc(FlagPreserve, buildSliceCompletionFunc(rsync.PreserveOptions))
+	cmd.RegisterFlagCompletionFunc(FlagLargeFileMode, buildSliceCompletionFunc(rsync.LargeFileModes))
+	cmd.RegisterFlagCompletionFunc(FlagChown, completionFuncNoFileComplete)
+	cmd.RegisterFlagCompletionFunc(FlagUserMap, completionFuncNoFileComplete)
+	cmd.RegisterFlagCompletionFunc(FlagGroupMap, completionFuncNoFileComplete)
+	cmd.RegisterFlagCompletionFunc(FlagParallel, completionFuncNoFileComplete)
+	cmd.RegisterFlagCompletionFunc(FlagBwLimit, completionFuncNoFileComplete)
+	cmd.RegisterFlagCompletionFunc(FlagRsyncRetries, completionFuncNoFileComplete)
+	cmd.RegisterFlagCompletionFunc(FlagRsyncRetryBackoff, completionFuncNoFileComplete)
+	cmd.RegisterFlagCompletionFunc(FlagWindow, completionFuncNoFileComplete)
+	cmd.RegisterFlagCompletionFunc(FlagInclude, completionFuncNoFileComplete)
+	cmd.RegisterFlagCompletionFunc(FlagExclude, completionFuncNoFileComplete)
+	cmd.RegisterFlagCompletionFunc(FlagRootlessUID, completionFuncNoFileComplete)
+	cmd.RegisterFlagCompletionFunc(FlagRelaySSH, completionFuncNoFileComplete)
+	cmd.RegisterFlagCompletionFunc(FlagSSHProxyJump, completionFuncNoFileComplete)
+	cmd.RegisterFlagCompletionFunc(FlagSSHCiphers, completionFuncNoFileComplete)
+	cmd.RegisterFlagCompletionFunc(FlagSSHOption, completionFuncNoFileComplete)
+
+	cmd.RegisterFlagCompletionFunc(FlagStrategyTimeout, completionFuncNoFileComplete)
+	cmd.RegisterFlagCompletionFunc(FlagStrategyInstallTimeout, completionFuncNoFileComplete)
+	cmd.RegisterFlagCompletionFunc(FlagStrategyRetries, completionFuncNoFileComplete)
+	cmd.RegisterFlagCompletionFunc(FlagStrategyRetryBackoff, completionFuncNoFileComplete)
 
 	cmd.RegisterFlagCompletionFunc(FlagHelmSet, completionFuncNoFileComplete)
 	cmd.RegisterFlagCompletionFunc(FlagHelmSetString, completionFuncNoFileComplete)
@@ -201,7 +273,8 @@ func setMigrateCmdFlags(cmd *cobra.Command, logLevels, logFormats []string, lega
 	flags.BoolP(FlagNoProgressBar, "b", false, "do not display a progress bar")
 	flags.BoolP(FlagSourceMountReadOnly, "R", true, "mount the source PVC in ReadOnly mode")
 	flags.StringSliceP(FlagStrategies, "s", strategy.DefaultStrategies,
-		"the comma-separated list of strategies to be used in the given order")
+		"the comma-separated list of strategies to be used in the given order, "+
+			"or "+strategy.AutoStrategy+" to rank all strategies by their estimated feasibility")
 	flags.StringP(FlagSSHKeyAlgorithm, "a", ssh.Ed25519KeyAlgorithm,
 		"ssh key algorithm to be used. Valid values are "+strings.Join(ssh.KeyAlgorithms, ","))
 	flags.StringP(FlagDestHostOverride, "H", "",
@@ -211,7 +284,63 @@ func setMigrateCmdFlags(cmd *cobra.Command, logLevels, logFormats []string, lega
 			"Has no effect for mnt2 and local strategies")
 	flags.Duration(FlagLBSvcTimeout, lbSvcTimeoutDefault, fmt.Sprintf("timeout for the load balancer service to "+
 		"receive an external IP. Only used by the %s strategy", strategy.LbSvcStrategy))
+	flags.String(FlagNodePortAddressType, string(corev1.NodeExternalIP), fmt.Sprintf("the preferred node "+
+		"address type to reach the node port service on, falls back to the other types if the node has no "+
+		"such address. Valid values are %s. Only used by the %s strategy",
+		strings.Join(k8s.NodeAddressTypes, ","), strategy.NodePortStrategy))
+	flags.String(FlagFidelity, rsync.FidelityDefault, "the preset of the file attributes to preserve. "+
+		"Valid values are "+strings.Join(rsync.Fidelities, ",")+". "+rsync.FidelityDefault+
+		" preserves what rsync -a preserves, "+rsync.FidelityFull+" preserves everything --"+FlagPreserve+" can")
+	flags.StringSlice(FlagPreserve, nil, "the file attributes to preserve in addition to the --"+FlagFidelity+
+		" preset. Valid values are "+strings.Join(rsync.PreserveOptions, ",")+". Only supported by the rsync engine")
+	flags.StringSlice(FlagLargeFileMode, nil, "how to resume the interrupted transfers of large files "+
+		"instead of resending them from the start. Valid values are "+strings.Join(rsync.LargeFileModes, ",")+
+		", see the docs for details. Only supported by the rsync engine")
+	flags.String(FlagChown, "", "the USER:GROUP to own all the migrated files, by name or ID, e.g. 1000:1000 or :1000 "+
+		"to change only the group. Only supported by the rsync engine")
+	flags.StringSlice(FlagUserMap, nil, "FROM:TO rules mapping the owners of the source files to the ones on the "+
+		"destination, e.g. 1000:2000 or *:2000, see --usermap of rsync. Cannot be combined with --"+FlagChown+
+		". Only supported by the rsync engine")
+	flags.StringSlice(FlagGroupMap, nil, "FROM:TO rules mapping the groups of the source files to the ones on the "+
+		"destination, like --"+FlagUserMap+". Cannot be combined with --"+FlagChown+
+		". Only supported by the rsync engine")
+	flags.Bool(FlagMatchDestFSGroup, false, "change the group of all the migrated files to the fsGroup of "+
+		"the pods or the workloads using the destination PVC, so that they can read the data. "+
+		"Only supported by the rsync engine")
+	flags.Int(FlagParallel, 1, "the number of concurrent rsync processes to transfer the data with, "+
+		"each copying a part of the top-level files and directories. Speeds up the transfers over high-latency "+
+		"links. Not supported by the tar engine")
+	flags.Bool(FlagAllowVanishedFiles, false, "treat the source files deleted during the transfer as a "+
+		"success instead of a failure, e.g. when the source is in use. Only supported by the rsync engine")
+	flags.String(FlagChangesLog, "", "path of a local file to write the files created, updated and deleted "+
+		"on the destination into, as JSON lines. Only supported by the rsync engine")
+	flags.Int(FlagRsyncRetries, rsyncRetriesDefault, "the number of times the transfer job is re-created "+
+		"after a failure which might succeed when retried, e.g. a dropped connection, before the attempt fails. "+
+		"Only used by the strategies which run a transfer job")
+	flags.Duration(FlagRsyncRetryBackoff, rsyncRetryBackoffDefault, "the wait before the first re-creation "+
+		"of the transfer job, doubled on each retry up to 5m")
+	flags.String(FlagBwLimit, "", "the maximum transfer rate in bytes per second, e.g. 500K or 10M, "+
+		"in KiB/s if it has no suffix. Unlimited by default. Not supported by the tar engine")
+	flags.String(FlagWindow, "", "the daily time window in HH:MM-HH:MM form to transfer the data in, "+
+		"e.g. 22:00-06:00, in the local time of this machine. The transfer is paused outside of it "+
+		"and resumed when it opens again")
+	flags.StringArray(FlagInclude, nil, "pattern of the files to migrate even if they match an --"+FlagExclude+
+		" pattern, in the syntax of rsync (can specify multiple)")
+	flags.StringArray(FlagExclude, nil, "pattern of the files not to migrate, in the syntax of rsync, "+
+		"e.g. lost+found or /cache/ (can specify multiple)")
+	flags.String(FlagFilterFile, "", "path of a file with rsync filter rules, one per line, "+
+		"applied after the --"+FlagInclude+" and --"+FlagExclude+" patterns. Only supported by the rsync engine")
+	setRootlessFlags(flags)
+	flags.String(FlagRelaySSH, "", fmt.Sprintf("the SSH bastion in user@host[:port] form to tunnel the "+
+		"traffic through, which both clusters can connect to. Only used by the %s strategy", strategy.RelayStrategy))
+	flags.String(FlagRelaySSHKey, "", fmt.Sprintf("path of the private key to authenticate to the SSH bastion "+
+		"given by --%s with", FlagRelaySSH))
 	flags.Bool(FlagCompress, true, "compress data during migration ('-z' flag of rsync)")
+	flags.String(FlagEngine, transfer.DefaultEngine, "the tool to copy the data with. Valid values are "+
+		strings.Join(transfer.Engines, ",")+". The job image must contain the tool, see the docs for details")
+
+	setStrategyPolicyFlags(flags)
+	setSSHFlags(flags)
 
 	flags.DurationP(FlagHelmTimeout, "t", 1*time.Minute, "install/uninstall timeout for helm releases")
 	flags.StringSliceP(FlagHelmValues, "f", nil,
@@ -253,6 +382,179 @@ func runMigration(cmd *cobra.Command, args []string) error {
 		dest, _ = flags.GetString(FlagDest)
 	}
 
+	request := buildRequest(flags, buildSrcPVCInfo(flags, src), buildDestPVCInfo(flags, dest))
+
+	if request.StrategyPolicies, err = buildStrategyPolicies(flags); err != nil {
+		return err
+	}
+
+	if request.SSHOptions, err = buildSSHOptions(flags); err != nil {
+		return err
+	}
+
+	if request.FilterRules, err = readFilterFile(flags); err != nil {
+		return err
+	}
+
+	fidelity, _ := flags.GetString(FlagFidelity)
+	preserve, _ := flags.GetStringSlice(FlagPreserve)
+
+	if request.Preserve, err = rsync.ParsePreserve(fidelity, preserve); err != nil {
+		return fmt.Errorf("failed to parse preserve options: %w", err)
+	}
+
+	largeFileModes, _ := flags.GetStringSlice(FlagLargeFileMode)
+
+	if request.LargeFile, err = rsync.ParseLargeFile(largeFileModes); err != nil {
+		return fmt.Errorf("failed to parse large file modes: %w", err)
+	}
+
+	if err = validateOwnership(request); err != nil {
+		return err
+	}
+
+	if request.Parallel < 1 {
+		return fmt.Errorf("--%s must be at least 1", FlagParallel)
+	}
+
+	if request.RsyncRetries < 0 || request.RsyncRetryBackoff < 0 {
+		return fmt.Errorf("--%s and --%s cannot be negative", FlagRsyncRetries, FlagRsyncRetryBackoff)
+	}
+
+	if err = rsync.ValidateBwLimit(request.BwLimit); err != nil {
+		return fmt.Errorf("failed to validate --%s: %w", FlagBwLimit, err)
+	}
+
+	if window, _ := flags.GetString(FlagWindow); window != "" {
+		if request.Window, err = migration.ParseWindow(window); err != nil {
+			return fmt.Errorf("failed to parse --%s: %w", FlagWindow, err)
+		}
+	}
+
+	if request.RelaySSH != "" && request.RelaySSHKeyPath == "" {
+		return fmt.Errorf("--%s is required when --%s is set", FlagRelaySSHKey, FlagRelaySSH)
+	}
+
+	if (len(request.SSHOptions.ProxyJump) > 0) != (request.SSHProxyJumpKeyPath != "") {
+		return fmt.Errorf("--%s and --%s need to be set together", FlagSSHProxyJump, FlagSSHProxyJumpKey)
+	}
+
+	if err = validateRootless(request); err != nil {
+		return err
+	}
+
+	if request.ChangesLogPath != "" && request.Engine != "" && request.Engine != transfer.RsyncEngine {
+		return fmt.Errorf("--%s is only supported by the %s engine", FlagChangesLog, transfer.RsyncEngine)
+	}
+
+	// truncated only after the validation of the flags, so that a rejected migration keeps the log of the previous one
+	if err = createChangesLog(request.ChangesLogPath); err != nil {
+		return err
+	}
+
+	logger.Info("🚀 Starting migration")
+
+	if request.DeleteExtraneousFiles {
+		logger.Info("❕ Extraneous files will be deleted from the destination")
+	}
+
+	if err := migrator.New().Run(ctx, request, logger); err != nil {
+		return fmt.Errorf("migration failed: %w", err)
+	}
+
+	return nil
+}
+
+// validateOwnership returns an error if the owners of the files are changed in conflicting ways.
+func validateOwnership(request *migration.Request) error {
+	if err := request.Ownership.Validate(); err != nil {
+		return fmt.Errorf("invalid --%s, --%s or --%s: %w", FlagChown, FlagUserMap, FlagGroupMap, err)
+	}
+
+	if request.MatchDestFSGroup && (request.Ownership.Chown != "" || len(request.Ownership.GroupMap) > 0) {
+		return fmt.Errorf("--%s cannot be combined with --%s and --%s", FlagMatchDestFSGroup, FlagChown, FlagGroupMap)
+	}
+
+	if request.NoChown && (!request.Ownership.Empty() || request.MatchDestFSGroup) {
+		return fmt.Errorf("--%s cannot be combined with the flags changing the owners of the files", FlagNoChown)
+	}
+
+	return nil
+}
+
+// setRootlessFlags sets the flags of running the pods as a non-root user, shared by the commands running sshd.
+func setRootlessFlags(flags *flag.FlagSet) {
+	flags.Bool(FlagRootless, false, "run the sshd and rsync pods as a non-root user, compliant with the "+
+		"restricted Pod Security Standard. The ownership of the files cannot be preserved, requires --"+FlagNoChown)
+	flags.Int64(FlagRootlessUID, rootlessUIDDefault, "the UID, GID and fsGroup of the pods with --"+FlagRootless)
+}
+
+// validateRootless fails early if the request cannot be run by the non-root user of the rootless mode.
+func validateRootless(request *migration.Request) error {
+	if !request.Rootless {
+		return nil
+	}
+
+	if request.RootlessUID <= 0 {
+		return fmt.Errorf("--%s must be a positive number", FlagRootlessUID)
+	}
+
+	if !request.NoChown {
+		return fmt.Errorf("--%s cannot preserve the ownership of the files, as the transfer runs as a non-root "+
+			"user; pass --%s to migrate them without it", FlagRootless, FlagNoChown)
+	}
+
+	return nil
+}
+
+// createChangesLog creates the changes log file, truncating it if it exists, so that it contains only the changes
+// of this migration and an unwritable path fails before the migration starts.
+func createChangesLog(path string) error {
+	if path == "" {
+		return nil
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create changes log: %w", err)
+	}
+
+	if err = file.Close(); err != nil {
+		return fmt.Errorf("failed to close changes log: %w", err)
+	}
+
+	return nil
+}
+
+// readFilterFile returns the content of the filter file given by the flags, validating the patterns as well.
+func readFilterFile(flags *flag.FlagSet) (string, error) {
+	includes, _ := flags.GetStringArray(FlagInclude)
+	excludes, _ := flags.GetStringArray(FlagExclude)
+
+	filter := rsync.Filter{Includes: includes, Excludes: excludes}
+	if err := filter.Validate(); err != nil {
+		return "", fmt.Errorf("invalid filter: %w", err)
+	}
+
+	path, _ := flags.GetString(FlagFilterFile)
+	if path == "" {
+		return "", nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read filter file: %w", err)
+	}
+
+	if strings.TrimSpace(string(data)) == "" {
+		return "", fmt.Errorf("filter file is empty: %s", path)
+	}
+
+	return string(data), nil
+}
+
+// buildRequest builds the request from the flags of the command. Flags not defined by the command are left empty.
+func buildRequest(flags *flag.FlagSet, source, dest *migration.PVCInfo) *migration.Request {
 	ignoreMounted, _ := flags.GetBool(FlagIgnoreMounted)
 	srcMountReadOnly, _ := flags.GetBool(FlagSourceMountReadOnly)
 	noChown, _ := flags.GetBool(FlagNoChown)
@@ -268,11 +570,30 @@ func runMigration(cmd *cobra.Command, args []string) error {
 	destHostOverride, _ := flags.GetString(FlagDestHostOverride)
 	lbSvcTimeout, _ := flags.GetDuration(FlagLBSvcTimeout)
 	compress, _ := flags.GetBool(FlagCompress)
-
+	nodePortAddressType, _ := flags.GetString(FlagNodePortAddressType)
 	deleteExtraneousFiles, _ := flags.GetBool(FlagDestDeleteExtraneousFiles)
-	request := migration.Request{
-		Source:                buildSrcPVCInfo(flags, src),
-		Dest:                  buildDestPVCInfo(flags, dest),
+	engine, _ := flags.GetString(FlagEngine)
+	bwLimit, _ := flags.GetString(FlagBwLimit)
+	parallel, _ := flags.GetInt(FlagParallel)
+	allowVanishedFiles, _ := flags.GetBool(FlagAllowVanishedFiles)
+	changesLog, _ := flags.GetString(FlagChangesLog)
+	rsyncRetries, _ := flags.GetInt(FlagRsyncRetries)
+	rsyncRetryBackoff, _ := flags.GetDuration(FlagRsyncRetryBackoff)
+	includes, _ := flags.GetStringArray(FlagInclude)
+	excludes, _ := flags.GetStringArray(FlagExclude)
+	relaySSH, _ := flags.GetString(FlagRelaySSH)
+	relaySSHKey, _ := flags.GetString(FlagRelaySSHKey)
+	sshProxyJumpKey, _ := flags.GetString(FlagSSHProxyJumpKey)
+	rootless, _ := flags.GetBool(FlagRootless)
+	chown, _ := flags.GetString(FlagChown)
+	userMap, _ := flags.GetStringSlice(FlagUserMap)
+	groupMap, _ := flags.GetStringSlice(FlagGroupMap)
+	matchDestFSGroup, _ := flags.GetBool(FlagMatchDestFSGroup)
+	rootlessUID, _ := flags.GetInt64(FlagRootlessUID)
+
+	return &migration.Request{
+		Source:                source,
+		Dest:                  dest,
 		DeleteExtraneousFiles: deleteExtraneousFiles,
 		IgnoreMounted:         ignoreMounted,
 		SourceMountReadOnly:   srcMountReadOnly,
@@ -289,19 +610,24 @@ func runMigration(cmd *cobra.Command, args []string) error {
 		DestHostOverride:      destHostOverride,
 		LBSvcTimeout:          lbSvcTimeout,
 		Compress:              compress,
+		NodePortAddressType:   nodePortAddressType,
+		Engine:                engine,
+		BwLimit:               bwLimit,
+		Parallel:              parallel,
+		AllowVanishedFiles:    allowVanishedFiles,
+		ChangesLogPath:        changesLog,
+		RsyncRetries:          rsyncRetries,
+		RsyncRetryBackoff:     rsyncRetryBackoff,
+		Includes:              includes,
+		Excludes:              excludes,
+		RelaySSH:              relaySSH,
+		RelaySSHKeyPath:       relaySSHKey,
+		SSHProxyJumpKeyPath:   sshProxyJumpKey,
+		Rootless:              rootless,
+		Ownership:             rsync.Ownership{Chown: chown, UserMap: userMap, GroupMap: groupMap},
+		MatchDestFSGroup:      matchDestFSGroup,
+		RootlessUID:           rootlessUID,
 	}
-
-	logger.Info("🚀 Starting migration")
-
-	if deleteExtraneousFiles {
-		logger.Info("❕ Extraneous files will be deleted from the destination")
-	}
-
-	if err := migrator.New().Run(ctx, &request, logger); err != nil {
-		return fmt.Errorf("migration failed: %w", err)
-	}
-
-	return nil
 }
 
 //nolint:nonamedreturns
@@ -0,0 +1,92 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	flag "github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+
+	"github.com/utkuozdemir/pv-migrate/migration"
+	"github.com/utkuozdemir/pv-migrate/strategy"
+)
+
+const (
+	FlagStrategyConfig         = "strategy-config"
+	FlagStrategyTimeout        = "strategy-timeout"
+	FlagStrategyInstallTimeout = "strategy-install-timeout"
+	FlagStrategyRetries        = "strategy-retries"
+	FlagStrategyRetryBackoff   = "strategy-retry-backoff"
+)
+
+func setStrategyPolicyFlags(flags *flag.FlagSet) {
+	flags.String(FlagStrategyConfig, "", "path of a YAML file with the timeouts and retries of the strategies, "+
+		"keyed by strategy name, see the docs for the format. The --strategy-* flags take precedence over it")
+	flags.StringToString(FlagStrategyTimeout, nil, "the deadline of a single attempt by strategy, "+
+		"e.g. svc=10m,lbsvc=20m. Unlimited by default")
+	flags.StringToString(FlagStrategyInstallTimeout, nil, "the timeout of the helm release installations "+
+		"by strategy, e.g. lbsvc=5m. Defaults to --"+FlagHelmTimeout+
+		" (the larger of it and --"+FlagLBSvcTimeout+" for "+strategy.LbSvcStrategy+")")
+	flags.StringToInt(FlagStrategyRetries, nil, "the number of times a transiently failed attempt "+
+		"is retried before moving on to the next strategy, by strategy, e.g. svc=2. Defaults to 0")
+	flags.StringToString(FlagStrategyRetryBackoff, nil, "the wait before the first retry of an attempt "+
+		"by strategy, doubled on each subsequent retry, e.g. svc=30s. Defaults to 10s")
+}
+
+// buildStrategyPolicies builds the policies of the strategies from the config file and the flags.
+func buildStrategyPolicies(flags *flag.FlagSet) (map[string]migration.StrategyPolicy, error) {
+	policies := make(map[string]migration.StrategyPolicy)
+
+	if configPath, _ := flags.GetString(FlagStrategyConfig); configPath != "" {
+		data, err := os.ReadFile(configPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read strategy config file: %w", err)
+		}
+
+		if err = yaml.Unmarshal(data, &policies); err != nil {
+			return nil, fmt.Errorf("failed to parse strategy config file: %w", err)
+		}
+	}
+
+	durationFlags := map[string]func(policy *migration.StrategyPolicy, d time.Duration){
+		FlagStrategyTimeout:        func(policy *migration.StrategyPolicy, d time.Duration) { policy.Timeout = d },
+		FlagStrategyInstallTimeout: func(policy *migration.StrategyPolicy, d time.Duration) { policy.InstallTimeout = d },
+		FlagStrategyRetryBackoff:   func(policy *migration.StrategyPolicy, d time.Duration) { policy.RetryBackoff = d },
+	}
+
+	for flagName, set := range durationFlags {
+		values, _ := flags.GetStringToString(flagName)
+
+		for name, value := range values {
+			duration, err := time.ParseDuration(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid duration for strategy %s in --%s: %w", name, flagName, err)
+			}
+
+			policy := policies[name]
+			set(&policy, duration)
+			policies[name] = policy
+		}
+	}
+
+	retries, _ := flags.GetStringToInt(FlagStrategyRetries)
+	for name, value := range retries {
+		policy := policies[name]
+		policy.Retries = value
+		policies[name] = policy
+	}
+
+	for name, policy := range policies {
+		if !slices.Contains(strategy.AllStrategies, name) {
+			return nil, fmt.Errorf("unknown strategy in the strategy policies: %s", name)
+		}
+
+		if policy.Timeout < 0 || policy.InstallTimeout < 0 || policy.RetryBackoff < 0 || policy.Retries < 0 {
+			return nil, fmt.Errorf("negative timeout or retries for strategy %s", name)
+		}
+	}
+
+	return policies, nil
+}
@@ -0,0 +1,57 @@
+package app
+
+import (
+	"fmt"
+
+	flag "github.com/spf13/pflag"
+
+	"github.com/utkuozdemir/pv-migrate/rsync"
+)
+
+const (
+	FlagSSHProxyJump         = "ssh-proxy-jump"
+	FlagSSHProxyJumpKey      = "ssh-proxy-jump-key"
+	FlagSSHCiphers           = "ssh-ciphers"
+	FlagSSHConnectTimeout    = "ssh-connect-timeout"
+	FlagSSHKeepAliveInterval = "ssh-keepalive-interval"
+	FlagSSHOption            = "ssh-option"
+)
+
+func setSSHFlags(flags *flag.FlagSet) {
+	flags.StringSlice(FlagSSHProxyJump, nil, "the [user@]host[:port] hosts to jump through to reach the sshd, "+
+		fmt.Sprintf("in order (ProxyJump of ssh). They are connected to with the key given by --%s "+
+			"and the same options as the sshd", FlagSSHProxyJumpKey))
+	flags.String(FlagSSHProxyJumpKey, "", fmt.Sprintf("path of the private key to authenticate to the jump hosts "+
+		"given by --%s with. It is mounted into the transfer job", FlagSSHProxyJump))
+	flags.StringSlice(FlagSSHCiphers, nil, "the ssh ciphers allowed, in order of preference, "+
+		"e.g. aes128-gcm@openssh.com. Defaults to the ones of ssh")
+	flags.Duration(FlagSSHConnectTimeout, rsync.DefaultSSHConnectTimeout,
+		"the timeout of establishing the ssh connection")
+	flags.Duration(FlagSSHKeepAliveInterval, 0, "the interval of the keepalive messages sent over the ssh "+
+		"connection, to keep it open during long idle phases like building the file list. Disabled by default")
+	flags.StringArray(FlagSSHOption, nil, "additional ssh option in Key=Value form, "+
+		"as passed to the -o flag of ssh (can specify multiple). Takes precedence over the options set by pv-migrate")
+}
+
+// buildSSHOptions builds the ssh options of the transfer from the flags and validates them.
+func buildSSHOptions(flags *flag.FlagSet) (rsync.SSHOptions, error) {
+	proxyJump, _ := flags.GetStringSlice(FlagSSHProxyJump)
+	ciphers, _ := flags.GetStringSlice(FlagSSHCiphers)
+	connectTimeout, _ := flags.GetDuration(FlagSSHConnectTimeout)
+	keepAliveInterval, _ := flags.GetDuration(FlagSSHKeepAliveInterval)
+	extraOptions, _ := flags.GetStringArray(FlagSSHOption)
+
+	opts := rsync.SSHOptions{
+		ProxyJump:           proxyJump,
+		Ciphers:             ciphers,
+		ConnectTimeout:      connectTimeout,
+		ServerAliveInterval: keepAliveInterval,
+		ExtraOptions:        extraOptions,
+	}
+
+	if err := opts.Validate(); err != nil {
+		return rsync.SSHOptions{}, fmt.Errorf("invalid ssh options: %w", err)
+	}
+
+	return opts, nil
+}
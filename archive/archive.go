@@ -0,0 +1,334 @@
+package archive
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	CompressionAuto = "auto"
+	CompressionZstd = "zstd"
+	CompressionGzip = "gzip"
+	CompressionNone = "none"
+
+	// ManifestVersion is the version of the manifest format written into the archives.
+	ManifestVersion = 1
+
+	manifestName = ".pv-migrate/manifest.json"
+	checksumName = ".pv-migrate/checksum.sha256"
+	dataPrefix   = "data/"
+
+	metadataFileMode = 0o644
+)
+
+var (
+	Compressions = []string{CompressionAuto, CompressionZstd, CompressionGzip, CompressionNone}
+
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	gzipMagic = []byte{0x1f, 0x8b}
+
+	ErrChecksumMismatch = errors.New("archive checksum mismatch")
+)
+
+// Manifest describes the contents of an archive.
+type Manifest struct {
+	Version   int                              `json:"version"`
+	CreatedAt time.Time                        `json:"createdAt"`
+	Namespace string                           `json:"namespace"`
+	Name      string                           `json:"name"`
+	Path      string                           `json:"path"`
+	Spec      corev1.PersistentVolumeClaimSpec `json:"spec"`
+}
+
+// CompressionForFile returns the compression to be used for the archive file with the given name,
+// based on its extension.
+func CompressionForFile(name string) string {
+	switch {
+	case strings.HasSuffix(name, ".zst"), strings.HasSuffix(name, ".tzst"):
+		return CompressionZstd
+	case strings.HasSuffix(name, ".gz"), strings.HasSuffix(name, ".tgz"):
+		return CompressionGzip
+	default:
+		return CompressionNone
+	}
+}
+
+// Write reads the tar stream from src and writes it into dst as an archive,
+// prefixed by the manifest and followed by the checksum of the data. It returns the checksum.
+func Write(dst io.Writer, src io.Reader, manifest *Manifest, compression string) (string, error) {
+	compressed, err := compressWriter(dst, compression)
+	if err != nil {
+		return "", err
+	}
+
+	tarWriter := tar.NewWriter(compressed)
+
+	manifestBytes, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+
+	if err = writeMetadataFile(tarWriter, manifestName, manifestBytes); err != nil {
+		return "", err
+	}
+
+	checksum := sha256.New()
+	tarReader := tar.NewReader(src)
+
+	for {
+		header, nextErr := tarReader.Next()
+		if errors.Is(nextErr, io.EOF) {
+			break
+		}
+
+		if nextErr != nil {
+			return "", fmt.Errorf("failed to read tar stream: %w", nextErr)
+		}
+
+		archiveName := dataPrefix + strings.TrimPrefix(header.Name, "./")
+
+		if err = copyEntry(tarWriter, tarReader, header, archiveName, archiveName, checksum); err != nil {
+			return "", err
+		}
+	}
+
+	sum := hex.EncodeToString(checksum.Sum(nil))
+
+	if err = writeMetadataFile(tarWriter, checksumName, []byte(sum)); err != nil {
+		return "", err
+	}
+
+	if err = tarWriter.Close(); err != nil {
+		return "", fmt.Errorf("failed to close tar writer: %w", err)
+	}
+
+	if err = compressed.Close(); err != nil {
+		return "", fmt.Errorf("failed to close compressed writer: %w", err)
+	}
+
+	return sum, nil
+}
+
+// Verify reads the whole archive from src and verifies its checksum.
+func Verify(src io.Reader) (*Manifest, error) {
+	return read(src, nil)
+}
+
+// Extract reads the archive from src and writes the data in it as a tar stream into dst.
+//
+// The checksum is only known after all the data is written, so callers should Verify the archive first.
+func Extract(dst io.Writer, src io.Reader) (*Manifest, error) {
+	return read(src, dst)
+}
+
+//nolint:cyclop
+func read(src io.Reader, dst io.Writer) (*Manifest, error) {
+	decompressed, err := decompressReader(src)
+	if err != nil {
+		return nil, err
+	}
+
+	defer decompressed.Close()
+
+	var (
+		manifest  *Manifest
+		storedSum string
+		tarWriter *tar.Writer
+		checksum  = sha256.New()
+		tarReader = tar.NewReader(decompressed)
+	)
+
+	if dst != nil {
+		tarWriter = tar.NewWriter(dst)
+	}
+
+	for {
+		header, nextErr := tarReader.Next()
+		if errors.Is(nextErr, io.EOF) {
+			break
+		}
+
+		if nextErr != nil {
+			return nil, fmt.Errorf("failed to read archive: %w", nextErr)
+		}
+
+		switch {
+		case header.Name == manifestName:
+			if manifest, err = readManifest(tarReader); err != nil {
+				return nil, err
+			}
+		case header.Name == checksumName:
+			sumBytes, readErr := io.ReadAll(tarReader)
+			if readErr != nil {
+				return nil, fmt.Errorf("failed to read checksum: %w", readErr)
+			}
+
+			storedSum = strings.TrimSpace(string(sumBytes))
+		case strings.HasPrefix(header.Name, dataPrefix):
+			if tarWriter == nil {
+				hashHeader(checksum, header)
+
+				if _, err = io.Copy(checksum, tarReader); err != nil {
+					return nil, fmt.Errorf("failed to read archive entry %s: %w", header.Name, err)
+				}
+
+				continue
+			}
+
+			name := "./" + strings.TrimPrefix(header.Name, dataPrefix)
+
+			if err = copyEntry(tarWriter, tarReader, header, header.Name, name, checksum); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if tarWriter != nil {
+		if err = tarWriter.Close(); err != nil {
+			return nil, fmt.Errorf("failed to close tar writer: %w", err)
+		}
+	}
+
+	if manifest == nil {
+		return nil, errors.New("archive has no manifest, it was not created by pv-migrate")
+	}
+
+	if sum := hex.EncodeToString(checksum.Sum(nil)); storedSum != sum {
+		return nil, fmt.Errorf("%w: expected %s, got %s", ErrChecksumMismatch, storedSum, sum)
+	}
+
+	return manifest, nil
+}
+
+// copyEntry copies the entry in the tar reader into the tar writer under the given name,
+// feeding it into the checksum with the name it has in the archive.
+func copyEntry(tarWriter *tar.Writer, tarReader *tar.Reader, header *tar.Header,
+	archiveName, name string, checksum hash.Hash,
+) error {
+	out := *header
+	out.Name = archiveName
+
+	hashHeader(checksum, &out)
+
+	out.Name = name
+	// let the writer pick a format which can hold the new name, and make sure the name is not overridden
+	out.Format = tar.FormatUnknown
+	delete(out.PAXRecords, "path")
+
+	if err := tarWriter.WriteHeader(&out); err != nil {
+		return fmt.Errorf("failed to write tar header for %s: %w", name, err)
+	}
+
+	if _, err := io.Copy(io.MultiWriter(tarWriter, checksum), tarReader); err != nil {
+		return fmt.Errorf("failed to copy tar entry %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// hashHeader feeds the fields of the header which are preserved across archiving and extracting into the hash.
+func hashHeader(h hash.Hash, header *tar.Header) {
+	fmt.Fprintf(h, "%s\x00%c\x00%o\x00%d\x00%d\x00%s\x00%d\x00",
+		header.Name, header.Typeflag, header.Mode, header.Uid, header.Gid, header.Linkname, header.Size)
+}
+
+func readManifest(reader io.Reader) (*Manifest, error) {
+	var manifest Manifest
+
+	if err := json.NewDecoder(reader).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("failed to decode manifest: %w", err)
+	}
+
+	if manifest.Version > ManifestVersion {
+		return nil, fmt.Errorf("unsupported manifest version %d, upgrade pv-migrate", manifest.Version)
+	}
+
+	return &manifest, nil
+}
+
+func writeMetadataFile(tarWriter *tar.Writer, name string, content []byte) error {
+	if err := tarWriter.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     metadataFileMode,
+		Size:     int64(len(content)),
+		ModTime:  time.Now(),
+	}); err != nil {
+		return fmt.Errorf("failed to write tar header for %s: %w", name, err)
+	}
+
+	if _, err := tarWriter.Write(content); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+
+	return nil
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+func compressWriter(dst io.Writer, compression string) (io.WriteCloser, error) {
+	switch compression {
+	case CompressionZstd:
+		writer, err := zstd.NewWriter(dst)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create zstd writer: %w", err)
+		}
+
+		return writer, nil
+	case CompressionGzip:
+		return gzip.NewWriter(dst), nil
+	case CompressionNone:
+		return nopWriteCloser{dst}, nil
+	default:
+		return nil, fmt.Errorf("unsupported compression: %s", compression)
+	}
+}
+
+// decompressReader detects the compression of the archive by its magic bytes and returns a reader for its contents.
+func decompressReader(src io.Reader) (io.ReadCloser, error) {
+	buffered := bufio.NewReader(src)
+
+	magic, err := buffered.Peek(len(zstdMagic))
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to read archive header: %w", err)
+	}
+
+	switch {
+	case bytes.HasPrefix(magic, zstdMagic):
+		decoder, err := zstd.NewReader(buffered)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create zstd reader: %w", err)
+		}
+
+		return decoder.IOReadCloser(), nil
+	case bytes.HasPrefix(magic, gzipMagic):
+		reader, err := gzip.NewReader(buffered)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create gzip reader: %w", err)
+		}
+
+		return reader, nil
+	default:
+		return io.NopCloser(buffered), nil
+	}
+}
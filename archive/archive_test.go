@@ -0,0 +1,127 @@
+package archive_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/utkuozdemir/pv-migrate/archive"
+)
+
+func TestCompressionForFile(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, archive.CompressionZstd, archive.CompressionForFile("backup.tar.zst"))
+	assert.Equal(t, archive.CompressionGzip, archive.CompressionForFile("backup.tar.gz"))
+	assert.Equal(t, archive.CompressionGzip, archive.CompressionForFile("backup.tgz"))
+	assert.Equal(t, archive.CompressionNone, archive.CompressionForFile("backup.tar"))
+}
+
+func TestWriteAndExtract(t *testing.T) {
+	t.Parallel()
+
+	for _, compression := range []string{archive.CompressionZstd, archive.CompressionGzip, archive.CompressionNone} {
+		t.Run(compression, func(t *testing.T) {
+			t.Parallel()
+
+			var archived bytes.Buffer
+
+			manifest := archive.Manifest{Version: archive.ManifestVersion, Namespace: "ns", Name: "pvc"}
+
+			sum, err := archive.Write(&archived, buildTestTarStream(t), &manifest, compression)
+			require.NoError(t, err)
+			assert.NotEmpty(t, sum)
+
+			verified, err := archive.Verify(bytes.NewReader(archived.Bytes()))
+			require.NoError(t, err)
+			assert.Equal(t, "pvc", verified.Name)
+
+			var extracted bytes.Buffer
+
+			_, err = archive.Extract(&extracted, bytes.NewReader(archived.Bytes()))
+			require.NoError(t, err)
+
+			assert.Equal(t, map[string]string{
+				"./":          "",
+				"./file.txt":  "DATA",
+				"./dir/":      "",
+				"./dir/other": "OTHER",
+			}, readTestTarStream(t, &extracted))
+		})
+	}
+}
+
+func TestVerifyChecksumMismatch(t *testing.T) {
+	t.Parallel()
+
+	var archived bytes.Buffer
+
+	_, err := archive.Write(&archived, buildTestTarStream(t), &archive.Manifest{}, archive.CompressionNone)
+	require.NoError(t, err)
+
+	tampered := bytes.Replace(archived.Bytes(), []byte("OTHER"), []byte("OTH3R"), 1)
+
+	_, err = archive.Verify(bytes.NewReader(tampered))
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, archive.ErrChecksumMismatch))
+}
+
+func buildTestTarStream(t *testing.T) io.Reader {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	tarWriter := tar.NewWriter(&buf)
+
+	for _, entry := range []struct {
+		name    string
+		content string
+		dir     bool
+	}{
+		{name: "./", dir: true},
+		{name: "./file.txt", content: "DATA"},
+		{name: "./dir/", dir: true},
+		{name: "./dir/other", content: "OTHER"},
+	} {
+		header := tar.Header{Name: entry.name, Mode: 0o644, Size: int64(len(entry.content)), Typeflag: tar.TypeReg}
+		if entry.dir {
+			header.Typeflag = tar.TypeDir
+			header.Mode = 0o755
+		}
+
+		require.NoError(t, tarWriter.WriteHeader(&header))
+
+		_, err := tarWriter.Write([]byte(entry.content))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, tarWriter.Close())
+
+	return &buf
+}
+
+func readTestTarStream(t *testing.T, reader io.Reader) map[string]string {
+	t.Helper()
+
+	result := map[string]string{}
+	tarReader := tar.NewReader(reader)
+
+	for {
+		header, err := tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			return result
+		}
+
+		require.NoError(t, err)
+
+		content, err := io.ReadAll(tarReader)
+		require.NoError(t, err)
+
+		result[header.Name] = string(content)
+	}
+}
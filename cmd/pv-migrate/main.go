@@ -9,6 +9,7 @@ import (
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
 	"github.com/utkuozdemir/pv-migrate/app"
+	"github.com/utkuozdemir/pv-migrate/strategy"
 )
 
 var (
@@ -28,6 +29,8 @@ func run() int {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	strategy.LoadPlugins()
+
 	rootCmd := app.BuildMigrateCmd(ctx, version, commit, date, false)
 
 	if err := rootCmd.ExecuteContext(ctx); err != nil {
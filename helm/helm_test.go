@@ -5,6 +5,9 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/engine"
 
 	"github.com/utkuozdemir/pv-migrate/helm"
 )
@@ -20,3 +23,35 @@ func TestLoadChart(t *testing.T) {
 	assert.NotEmpty(t, chart.Values, "chart values should not be empty")
 	assert.NotEmpty(t, chart.Templates, "chart templates should not be empty")
 }
+
+func TestRenderRsyncCommandIgnoresExtraArgs(t *testing.T) {
+	t.Parallel()
+
+	chart, err := helm.LoadChart()
+	require.NoError(t, err)
+
+	// a parallel transfer, where anything appended to the command would be an argument of the subshell
+	command := `(set -o pipefail; rsync -av /source/ /dest/ 2>&1 | tee /tmp/out; rc=$?; exit $rc)`
+
+	vals := map[string]any{
+		"rsync": map[string]any{
+			"enabled":   true,
+			"namespace": "ns",
+			"command":   command,
+			"extraArgs": "--partial --inplace",
+		},
+	}
+
+	renderVals, err := chartutil.ToRenderValues(chart, vals, chartutil.ReleaseOptions{Name: "rel", Namespace: "ns"}, nil)
+	require.NoError(t, err)
+
+	rendered, err := engine.Render(chart, renderVals)
+	require.NoError(t, err)
+
+	var configMap struct {
+		Data map[string]string `yaml:"data"`
+	}
+
+	require.NoError(t, yaml.Unmarshal([]byte(rendered["pv-migrate/templates/rsync/configmap.yaml"]), &configMap))
+	assert.Equal(t, command, configMap.Data["command"])
+}
@@ -48,3 +48,44 @@ app.kubernetes.io/instance: {{ .Release.Name }}
 {{- default "default" .Values.rsync.serviceAccount.name }}
 {{- end }}
 {{- end }}
+
+{{- define "pv-migrate.rootless.podSecurityContext" -}}
+runAsNonRoot: true
+runAsUser: {{ .Values.rootless.uid }}
+runAsGroup: {{ .Values.rootless.uid }}
+{{- if .Values.rootless.fsGroup }}
+fsGroup: {{ .Values.rootless.uid }}
+{{- end }}
+seccompProfile:
+  type: RuntimeDefault
+{{- end }}
+
+{{- define "pv-migrate.rootless.securityContext" -}}
+allowPrivilegeEscalation: false
+runAsNonRoot: true
+capabilities:
+  drop:
+    - ALL
+seccompProfile:
+  type: RuntimeDefault
+{{- end }}
+
+{{- /*
+The passwd file of the containers, so that ssh and sshd can look up the non-root user, which is not in the images.
+*/}}
+{{- define "pv-migrate.rootless.passwd" -}}
+root:x:0:0:root:/root:/bin/sh
+{{ .Values.rootless.user }}:x:{{ .Values.rootless.uid }}:{{ .Values.rootless.uid }}::/tmp:/bin/sh
+{{ end }}
+
+{{- /*
+The mode of the mounted secrets. Without the fsGroup, they are owned by root:root, so they need to be readable by
+others for the non-root user, which is the only user in the containers.
+*/}}
+{{- define "pv-migrate.secret.defaultMode" -}}
+{{- if not .Values.rootless.enabled }}0400{{ else if .Values.rootless.fsGroup }}0440{{ else }}0444{{ end }}
+{{- end }}
+
+{{- define "pv-migrate.sshd.port" -}}
+{{- if .Values.rootless.enabled }}{{ .Values.rootless.sshdPort }}{{ else }}22{{ end }}
+{{- end }}
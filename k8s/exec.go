@@ -0,0 +1,66 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+type ExecRequest struct {
+	// RestConfig is the kubernetes config
+	RestConfig *rest.Config
+	PodNs      string
+	PodName    string
+	Container  string
+	Command    []string
+	Stdin      io.Reader
+	Stdout     io.Writer
+	Stderr     io.Writer
+}
+
+// Exec runs the command in the container of the pod over the SPDY exec API,
+// streaming the given stdin, stdout and stderr. It blocks until the command exits.
+func Exec(ctx context.Context, req *ExecRequest) error {
+	targetURL, err := url.Parse(req.RestConfig.Host)
+	if err != nil {
+		return fmt.Errorf("failed to parse target url: %w", err)
+	}
+
+	targetURL.Path = path.Join(
+		targetURL.Path, "api", "v1", "namespaces", req.PodNs, "pods", req.PodName, "exec",
+	)
+
+	query := url.Values{}
+	query.Set("container", req.Container)
+	query.Set("stdin", strconv.FormatBool(req.Stdin != nil))
+	query.Set("stdout", strconv.FormatBool(req.Stdout != nil))
+	query.Set("stderr", strconv.FormatBool(req.Stderr != nil))
+
+	for _, arg := range req.Command {
+		query.Add("command", arg)
+	}
+
+	targetURL.RawQuery = query.Encode()
+
+	executor, err := remotecommand.NewSPDYExecutor(req.RestConfig, http.MethodPost, targetURL)
+	if err != nil {
+		return fmt.Errorf("failed to initialize executor: %w", err)
+	}
+
+	if err = executor.StreamWithContext(ctx, remotecommand.StreamOptions{
+		Stdin:  req.Stdin,
+		Stdout: req.Stdout,
+		Stderr: req.Stderr,
+	}); err != nil {
+		return fmt.Errorf("failed to exec in pod %s/%s: %w", req.PodNs, req.PodName, err)
+	}
+
+	return nil
+}
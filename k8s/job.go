@@ -6,22 +6,76 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"strings"
+	"time"
 
 	"golang.org/x/sync/errgroup"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes"
 
 	"github.com/utkuozdemir/pv-migrate/rsync/progress"
 )
 
+const (
+	jobPodsDeletionPollInterval = 2 * time.Second
+
+	// legacyControllerUIDLabel is the label which the job controller selected the pods of a job by, before
+	// batchv1.ControllerUidLabel.
+	legacyControllerUIDLabel = "controller-uid"
+)
+
+// JobFailedError is returned when the pod of a job terminates unsuccessfully.
+type JobFailedError struct {
+	Namespace string
+	Name      string
+	// ExitCode is the exit code of the first container of the pod which terminated with an error, -1 if unknown.
+	ExitCode int
+	// Message is the termination message of that container, which is the end of its logs
+	// if its terminationMessagePolicy is FallbackToLogsOnError.
+	Message string
+}
+
+func (e *JobFailedError) Error() string {
+	msg := fmt.Sprintf("job %s/%s failed", e.Namespace, e.Name)
+	if e.ExitCode >= 0 {
+		msg += fmt.Sprintf(" with exit code %d", e.ExitCode)
+	}
+
+	if lastLine := e.lastLine(); lastLine != "" {
+		msg += ": " + lastLine
+	}
+
+	return msg
+}
+
+// lastLine returns the last non-empty line of the message.
+func (e *JobFailedError) lastLine() string {
+	// the progress is updated in place with carriage returns, so they separate the lines as well
+	lines := strings.FieldsFunc(e.Message, func(r rune) bool { return r == '\n' || r == '\r' })
+
+	for i := len(lines) - 1; i >= 0; i-- {
+		if line := strings.TrimSpace(lines[i]); line != "" {
+			return line
+		}
+	}
+
+	return ""
+}
+
 // WaitForJobCompletion waits for the Kubernetes job to complete.
 //
-
+// The logs of the job are handled by a progress logger with the given options to display the progress.
+// The progress bar is shown only if it is requested by the options and can be displayed.
+// If the job fails, a *JobFailedError is returned.
 func WaitForJobCompletion(ctx context.Context, cli kubernetes.Interface,
-	namespace string, name string, progressBarRequested bool, logger *slog.Logger,
+	namespace string, name string, logOptions progress.LoggerOptions, logger *slog.Logger,
 ) (retErr error) {
 	canDisplayProgressBar := ctx.Value(progress.CanDisplayProgressBarContextKey{}) != nil
-	showProgressBar := progressBarRequested && canDisplayProgressBar
 	labelSelector := "job-name=" + name
 
 	pod, err := WaitForPod(ctx, cli, namespace, labelSelector)
@@ -31,32 +85,44 @@ func WaitForJobCompletion(ctx context.Context, cli kubernetes.Interface,
 
 	var eg errgroup.Group //nolint:varnamelen
 
+	logOptions.ShowProgressBar = logOptions.ShowProgressBar && canDisplayProgressBar
+	logOptions.Restreams = true
+	logOptions.LogStreamFunc = func(ctx context.Context) (io.ReadCloser, error) {
+		return cli.CoreV1().Pods(namespace).GetLogs(pod.Name,
+			&corev1.PodLogOptions{Follow: true}).Stream(ctx)
+	}
+
+	progressLogger := progress.NewLogger(logOptions)
+
 	defer func() {
 		retErr = errors.Join(retErr, eg.Wait())
+
+		if stats, ok := progressLogger.Stats(); ok && retErr == nil {
+			logger.Info("📊 Transfer summary", "stats", stats)
+		}
 	}()
 
 	tailCtx, tailCancel := context.WithCancel(ctx)
 	defer tailCancel()
 
-	progressLogger := progress.NewLogger(progress.LoggerOptions{
-		ShowProgressBar: showProgressBar,
-		LogStreamFunc: func(ctx context.Context) (io.ReadCloser, error) {
-			return cli.CoreV1().Pods(namespace).GetLogs(pod.Name,
-				&corev1.PodLogOptions{Follow: true}).Stream(ctx)
-		},
-	})
-
 	eg.Go(func() error {
 		return progressLogger.Start(tailCtx, logger)
 	})
 
-	phase, err := waitForPodTermination(ctx, cli, pod.Namespace, pod.Name)
+	terminatedPod, err := waitForPodTermination(ctx, cli, pod.Namespace, pod.Name)
 	if err != nil {
 		return err
 	}
 
-	if *phase != corev1.PodSucceeded {
-		return fmt.Errorf("job %s/%s failed", pod.Namespace, pod.Name)
+	if terminatedPod.Status.Phase != corev1.PodSucceeded {
+		exitCode, message := failedContainerStatus(terminatedPod)
+
+		return &JobFailedError{
+			Namespace: pod.Namespace,
+			Name:      pod.Name,
+			ExitCode:  exitCode,
+			Message:   message,
+		}
 	}
 
 	if err = progressLogger.MarkAsComplete(ctx); err != nil {
@@ -65,3 +131,122 @@ func WaitForJobCompletion(ctx context.Context, cli kubernetes.Interface,
 
 	return nil
 }
+
+// failedContainerStatus returns the exit code and the termination message of the first container of the pod
+// which terminated with an error, or -1 if there is none.
+func failedContainerStatus(pod *corev1.Pod) (int, string) {
+	for _, status := range pod.Status.ContainerStatuses {
+		if terminated := status.State.Terminated; terminated != nil && terminated.ExitCode != 0 {
+			return int(terminated.ExitCode), terminated.Message
+		}
+	}
+
+	return -1, ""
+}
+
+// WaitForJobPodTermination waits for the pod of the Kubernetes job to terminate and returns it.
+func WaitForJobPodTermination(ctx context.Context, cli kubernetes.Interface,
+	namespace, name string,
+) (*corev1.Pod, error) {
+	pod, err := WaitForPod(ctx, cli, namespace, "job-name="+name)
+	if err != nil {
+		return nil, err
+	}
+
+	return waitForPodTermination(ctx, cli, pod.Namespace, pod.Name)
+}
+
+// SuspendJob suspends or resumes the Kubernetes job.
+//
+// Suspending a job deletes its active pods, and resuming it creates new ones.
+func SuspendJob(ctx context.Context, cli kubernetes.Interface, namespace, name string, suspend bool) error {
+	patch := fmt.Sprintf(`{"spec":{"suspend":%t}}`, suspend)
+
+	if _, err := cli.BatchV1().Jobs(namespace).Patch(ctx, name, types.MergePatchType,
+		[]byte(patch), metav1.PatchOptions{}); err != nil {
+		return fmt.Errorf("failed to patch job %s/%s: %w", namespace, name, err)
+	}
+
+	return nil
+}
+
+// WaitForJobPodsDeletion waits until there are no pods of the Kubernetes job left, e.g. after suspending it.
+func WaitForJobPodsDeletion(ctx context.Context, cli kubernetes.Interface, namespace, name string) error {
+	listOptions := metav1.ListOptions{LabelSelector: "job-name=" + name}
+
+	if err := wait.PollUntilContextCancel(ctx, jobPodsDeletionPollInterval, true,
+		func(ctx context.Context) (bool, error) {
+			pods, err := cli.CoreV1().Pods(namespace).List(ctx, listOptions)
+			if err != nil {
+				return false, fmt.Errorf("failed to list pods: %w", err)
+			}
+
+			return len(pods.Items) == 0, nil
+		}); err != nil {
+		return fmt.Errorf("failed to wait for pods of job %s/%s to be deleted: %w", namespace, name, err)
+	}
+
+	return nil
+}
+
+// RecreateJob deletes the Kubernetes job with its pods and creates it again with the same spec,
+// so that its pod runs once more, e.g. after it failed. The new job is suspended if requested.
+func RecreateJob(ctx context.Context, cli kubernetes.Interface, namespace, name string, suspend bool) error {
+	jobs := cli.BatchV1().Jobs(namespace)
+
+	job, err := jobs.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get job %s/%s: %w", namespace, name, err)
+	}
+
+	propagationPolicy := metav1.DeletePropagationForeground
+	if err = jobs.Delete(ctx, name, metav1.DeleteOptions{PropagationPolicy: &propagationPolicy}); err != nil &&
+		!apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete job %s/%s: %w", namespace, name, err)
+	}
+
+	if err = wait.PollUntilContextCancel(ctx, jobPodsDeletionPollInterval, true,
+		func(ctx context.Context) (bool, error) {
+			_, err := jobs.Get(ctx, name, metav1.GetOptions{})
+			if apierrors.IsNotFound(err) {
+				return true, nil
+			}
+
+			if err != nil {
+				return false, fmt.Errorf("failed to get job: %w", err)
+			}
+
+			return false, nil
+		}); err != nil {
+		return fmt.Errorf("failed to wait for job %s/%s to be deleted: %w", namespace, name, err)
+	}
+
+	if err = WaitForJobPodsDeletion(ctx, cli, namespace, name); err != nil {
+		return err
+	}
+
+	recreated := batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   namespace,
+			Name:        name,
+			Labels:      job.Labels,
+			Annotations: job.Annotations,
+		},
+		Spec: *job.Spec.DeepCopy(),
+	}
+
+	// the selector of the pods is generated for the new job, by its new uid
+	recreated.Spec.Selector = nil
+	recreated.Spec.ManualSelector = nil
+
+	delete(recreated.Spec.Template.Labels, batchv1.ControllerUidLabel)
+	delete(recreated.Spec.Template.Labels, legacyControllerUIDLabel)
+
+	recreated.Spec.Suspend = &suspend
+
+	if _, err = jobs.Create(ctx, &recreated, metav1.CreateOptions{}); err != nil {
+		return fmt.Errorf("failed to create job %s/%s: %w", namespace, name, err)
+	}
+
+	return nil
+}
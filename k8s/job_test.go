@@ -0,0 +1,104 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestSuspendJob(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	job := batchv1.Job{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "rel-rsync"}}
+	pod := corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Namespace: "ns", Name: "rel-rsync-abc", Labels: map[string]string{"job-name": "rel-rsync"},
+	}}
+
+	cli := fake.NewSimpleClientset(&job, &pod)
+
+	require.NoError(t, SuspendJob(ctx, cli, "ns", "rel-rsync", true))
+
+	suspended, err := cli.BatchV1().Jobs("ns").Get(ctx, "rel-rsync", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.NotNil(t, suspended.Spec.Suspend)
+	assert.True(t, *suspended.Spec.Suspend)
+
+	// the fake clientset has no job controller to delete the pods of the suspended job
+	require.NoError(t, cli.CoreV1().Pods("ns").Delete(ctx, pod.Name, metav1.DeleteOptions{}))
+	require.NoError(t, WaitForJobPodsDeletion(ctx, cli, "ns", "rel-rsync"))
+
+	require.NoError(t, SuspendJob(ctx, cli, "ns", "rel-rsync", false))
+
+	resumed, err := cli.BatchV1().Jobs("ns").Get(ctx, "rel-rsync", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.NotNil(t, resumed.Spec.Suspend)
+	assert.False(t, *resumed.Spec.Suspend)
+
+	require.Error(t, SuspendJob(ctx, cli, "ns", "missing-rsync", true))
+}
+
+func TestRecreateJob(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	podLabels := map[string]string{
+		"job-name": "rel-rsync", "controller-uid": "abc", batchv1.ControllerUidLabel: "abc", "app": "pv-migrate",
+	}
+	job := batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "ns", Name: "rel-rsync", UID: "abc", ResourceVersion: "5",
+			Annotations: map[string]string{"meta.helm.sh/release-name": "rel"},
+		},
+		Spec: batchv1.JobSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{batchv1.ControllerUidLabel: "abc"}},
+			Template: corev1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Labels: podLabels}},
+		},
+		Status: batchv1.JobStatus{Failed: 1},
+	}
+
+	cli := fake.NewSimpleClientset(&job)
+
+	require.NoError(t, RecreateJob(ctx, cli, "ns", "rel-rsync", true))
+
+	recreated, err := cli.BatchV1().Jobs("ns").Get(ctx, "rel-rsync", metav1.GetOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, job.Annotations, recreated.Annotations)
+	assert.Nil(t, recreated.Spec.Selector)
+	assert.Equal(t, map[string]string{"job-name": "rel-rsync", "app": "pv-migrate"}, recreated.Spec.Template.Labels)
+	assert.Zero(t, recreated.Status.Failed)
+	assert.True(t, *recreated.Spec.Suspend)
+
+	// the labels of the deleted job are left as they are
+	assert.Len(t, job.Spec.Template.Labels, 4)
+
+	require.Error(t, RecreateJob(ctx, cli, "ns", "missing-rsync", false))
+}
+
+func TestJobFailedError(t *testing.T) {
+	t.Parallel()
+
+	err := JobFailedError{Namespace: "ns", Name: "rel-rsync-abc", ExitCode: -1}
+	assert.Equal(t, "job ns/rel-rsync-abc failed", err.Error())
+
+	err = JobFailedError{
+		Namespace: "ns", Name: "rel-rsync-abc", ExitCode: 23,
+		Message: "     1,000  10%  1.00MB/s    0:00:09\r     2,000  20%  1.00MB/s    0:00:08\n" +
+			"rsync error: some files/attrs were not transferred (code 23) at main.c(1338)\n\n",
+	}
+	assert.Equal(t, "job ns/rel-rsync-abc failed with exit code 23: "+
+		"rsync error: some files/attrs were not transferred (code 23) at main.c(1338)", err.Error())
+
+	err.Message = "     2,000  20%  1.00MB/s    0:00:08\r\n"
+	assert.Equal(t, "job ns/rel-rsync-abc failed with exit code 23: 2,000  20%  1.00MB/s    0:00:08", err.Error())
+}
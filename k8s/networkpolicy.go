@@ -0,0 +1,19 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// HasNetworkPolicies returns whether there are any NetworkPolicies in the namespace.
+func HasNetworkPolicies(ctx context.Context, cli kubernetes.Interface, namespace string) (bool, error) {
+	policies, err := cli.NetworkingV1().NetworkPolicies(namespace).List(ctx, metav1.ListOptions{Limit: 1})
+	if err != nil {
+		return false, fmt.Errorf("failed to list network policies in namespace %s: %w", namespace, err)
+	}
+
+	return len(policies.Items) > 0, nil
+}
@@ -0,0 +1,55 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// NodeAddressTypes are the node address types which can be used to reach a NodePort service.
+var NodeAddressTypes = []string{string(corev1.NodeExternalIP), string(corev1.NodeInternalIP)}
+
+// GetNodeAddress returns a reachable address of the node with the given name.
+//
+// The address of the preferred type is returned if the node has one,
+// otherwise it falls back to the ExternalIP and then to the InternalIP of the node.
+func GetNodeAddress(ctx context.Context, cli kubernetes.Interface,
+	name string, preferredType string,
+) (string, error) {
+	if preferredType != "" && !slices.Contains(NodeAddressTypes, preferredType) {
+		return "", fmt.Errorf("unsupported node address type: %s", preferredType)
+	}
+
+	node, err := cli.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get node %s: %w", name, err)
+	}
+
+	address := findNodeAddress(node, preferredType)
+	if address == "" {
+		return "", fmt.Errorf("node %s has no address of types %v", name, NodeAddressTypes)
+	}
+
+	return address, nil
+}
+
+func findNodeAddress(node *corev1.Node, preferredType string) string {
+	addressTypes := NodeAddressTypes
+	if preferredType != "" {
+		addressTypes = append([]string{preferredType}, NodeAddressTypes...)
+	}
+
+	for _, addressType := range addressTypes {
+		for _, address := range node.Status.Addresses {
+			if string(address.Type) == addressType && address.Address != "" {
+				return address.Address
+			}
+		}
+	}
+
+	return ""
+}
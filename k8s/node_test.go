@@ -0,0 +1,55 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestGetNodeAddress(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	cli := fake.NewSimpleClientset(
+		buildTestNode("node-1", corev1.NodeAddress{Type: corev1.NodeInternalIP, Address: "10.0.0.1"},
+			corev1.NodeAddress{Type: corev1.NodeExternalIP, Address: "1.2.3.4"}),
+		buildTestNode("node-2", corev1.NodeAddress{Type: corev1.NodeInternalIP, Address: "10.0.0.2"}),
+		buildTestNode("node-3", corev1.NodeAddress{Type: corev1.NodeHostName, Address: "node-3"}),
+	)
+
+	address, err := GetNodeAddress(ctx, cli, "node-1", "")
+	require.NoError(t, err)
+	assert.Equal(t, "1.2.3.4", address)
+
+	address, err = GetNodeAddress(ctx, cli, "node-1", string(corev1.NodeInternalIP))
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.1", address)
+
+	address, err = GetNodeAddress(ctx, cli, "node-2", string(corev1.NodeExternalIP))
+	require.NoError(t, err)
+	assert.Equal(t, "10.0.0.2", address)
+
+	_, err = GetNodeAddress(ctx, cli, "node-3", "")
+	require.Error(t, err)
+
+	_, err = GetNodeAddress(ctx, cli, "node-1", "Hostname")
+	require.Error(t, err)
+}
+
+func buildTestNode(name string, addresses ...corev1.NodeAddress) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Status: corev1.NodeStatus{
+			Addresses: addresses,
+		},
+	}
+}
@@ -74,8 +74,8 @@ func WaitForPod(ctx context.Context, cli kubernetes.Interface, namespace, labelS
 
 func waitForPodTermination(ctx context.Context, cli kubernetes.Interface,
 	namespace string, name string,
-) (*corev1.PodPhase, error) {
-	var result *corev1.PodPhase
+) (*corev1.Pod, error) {
+	var result *corev1.Pod
 
 	resCli := cli.CoreV1().Pods(namespace)
 	fieldSelector := fields.OneTermEqualSelector(metav1.ObjectNameField, name).String()
@@ -111,7 +111,7 @@ func waitForPodTermination(ctx context.Context, cli kubernetes.Interface,
 
 			phase := res.Status.Phase
 			if phase != corev1.PodRunning {
-				result = &phase
+				result = res
 
 				return true, nil
 			}
@@ -15,6 +15,41 @@ import (
 	watchtools "k8s.io/client-go/tools/watch"
 )
 
+// HasAssignedLoadBalancer returns whether there is a LoadBalancer service with an assigned address in the cluster,
+// which indicates that the cluster has a working load balancer implementation.
+func HasAssignedLoadBalancer(ctx context.Context, cli kubernetes.Interface) (bool, error) {
+	svcs, err := cli.CoreV1().Services(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return false, fmt.Errorf("failed to list services: %w", err)
+	}
+
+	for _, svc := range svcs.Items {
+		if svc.Spec.Type == corev1.ServiceTypeLoadBalancer && len(svc.Status.LoadBalancer.Ingress) > 0 {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// GetServiceNodePort returns the node port allocated to the given port of a NodePort service.
+func GetServiceNodePort(ctx context.Context, cli kubernetes.Interface,
+	namespace string, name string, port int,
+) (int, error) {
+	svc, err := cli.CoreV1().Services(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get service %s/%s: %w", namespace, name, err)
+	}
+
+	for _, svcPort := range svc.Spec.Ports {
+		if int(svcPort.Port) == port && svcPort.NodePort != 0 {
+			return int(svcPort.NodePort), nil
+		}
+	}
+
+	return 0, fmt.Errorf("service %s/%s has no node port allocated for port %d", namespace, name, port)
+}
+
 //nolint:funlen
 func GetServiceAddress(
 	ctx context.Context,
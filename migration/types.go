@@ -6,6 +6,7 @@ import (
 	"helm.sh/helm/v3/pkg/chart"
 
 	"github.com/utkuozdemir/pv-migrate/pvc"
+	"github.com/utkuozdemir/pv-migrate/rsync"
 )
 
 type PVCInfo struct {
@@ -35,6 +36,69 @@ type Request struct {
 	DestHostOverride      string
 	LBSvcTimeout          time.Duration
 	Compress              bool
+	NodePortAddressType   string
+	Engine                string
+	// SSHOptions are the options of the ssh connection of the transfer, for the strategies transferring over ssh.
+	SSHOptions rsync.SSHOptions
+	// SSHProxyJumpKeyPath is the path of the private key to authenticate to the jump hosts of SSHOptions with.
+	SSHProxyJumpKeyPath string
+	// Includes are the patterns of the files to transfer even if they match one of the Excludes.
+	Includes []string
+	// Excludes are the patterns of the files not to transfer.
+	Excludes []string
+	// FilterRules is the content of a file with rsync filter rules, one per line, applied after the includes
+	// and excludes.
+	FilterRules string
+	// Preserve are the attributes of the files preserved in addition to the ones rsync -a preserves.
+	Preserve rsync.Preserve
+	// Ownership changes the owners of the files on the destination instead of preserving the ones of the source.
+	Ownership rsync.Ownership
+	// MatchDestFSGroup changes the group of all the files to the fsGroup of the workload using the destination PVC,
+	// so that it can read the data right after the migration. It is resolved into the Ownership by the migrator.
+	MatchDestFSGroup bool
+	// LargeFile are the options of resuming the interrupted transfers of large files.
+	LargeFile rsync.LargeFile
+	// BwLimit is the maximum transfer rate, in the syntax of the --bwlimit flag of rsync, e.g. 10M. Unlimited if empty.
+	BwLimit string
+	// ChangesLogPath is the path of the local file to record the changes of the files on the destination to,
+	// as JSON lines. It is recreated for each run of the migration, and the changes of each attempt are appended
+	// to it. The changes are not recorded if empty.
+	ChangesLogPath string
+	// AllowVanishedFiles treats the files vanishing from the source during the transfer as a success,
+	// e.g. for live sources.
+	AllowVanishedFiles bool
+	// Parallel is the number of concurrent streams to transfer the data with. A single stream is used if less than 2.
+	Parallel int
+	// RsyncRetries is the number of times the transfer job is re-created after a failure
+	// which might succeed when retried, before the attempt fails.
+	RsyncRetries int
+	// RsyncRetryBackoff is the wait before the first re-creation of the transfer job, doubled on each subsequent one.
+	RsyncRetryBackoff time.Duration
+	// Rootless runs the transfer pods as the non-root RootlessUID, compliant with the restricted Pod Security Standard.
+	// The ownership of the files cannot be preserved in this mode.
+	Rootless bool
+	// RootlessUID is the UID, GID and fsGroup of the transfer pods in the Rootless mode.
+	RootlessUID int64
+	// Window is the daily time window the data is transferred in, the transfer is paused outside of it.
+	Window *Window
+	// RelaySSH is the user@host[:port] of the SSH bastion the relay strategy tunnels the traffic through.
+	RelaySSH string
+	// RelaySSHKeyPath is the path of the private key to authenticate to the bastion with.
+	RelaySSHKeyPath string
+	// StrategyPolicies are the timeouts and retries of the attempts, by strategy name.
+	StrategyPolicies map[string]StrategyPolicy
+}
+
+// StrategyPolicy configures the attempts of the migration with a strategy. Zero values are replaced by the defaults.
+type StrategyPolicy struct {
+	// Timeout is the deadline of a single attempt, unlimited if zero.
+	Timeout time.Duration `yaml:"timeout"`
+	// InstallTimeout is the timeout of each helm release installation of an attempt.
+	InstallTimeout time.Duration `yaml:"installTimeout"`
+	// Retries is the number of times a failed attempt is retried before moving on to the next strategy.
+	Retries int `yaml:"retries"`
+	// RetryBackoff is the wait before the first retry, doubled on each subsequent retry.
+	RetryBackoff time.Duration `yaml:"retryBackoff"`
 }
 
 type Migration struct {
@@ -48,4 +112,6 @@ type Attempt struct {
 	ID                    string
 	HelmReleaseNamePrefix string
 	Migration             *Migration
+	// InstallTimeout is the timeout of the helm release installations, defaults to the helm timeout if zero.
+	InstallTimeout time.Duration
 }
@@ -0,0 +1,96 @@
+package migration
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	windowTimeLayout = "15:04"
+	day              = 24 * time.Hour
+)
+
+// Window is a daily time window, in the local time of the machine running the migration.
+// It wraps around midnight if it ends before it starts, e.g. 22:00-06:00.
+type Window struct {
+	// Start and End are the offsets of the start and the end of the window from midnight.
+	Start time.Duration
+	End   time.Duration
+}
+
+// ParseWindow parses a window in the HH:MM-HH:MM form.
+func ParseWindow(s string) (*Window, error) {
+	startStr, endStr, ok := strings.Cut(s, "-")
+	if !ok {
+		return nil, fmt.Errorf("invalid window, expected HH:MM-HH:MM: %s", s)
+	}
+
+	start, err := time.Parse(windowTimeLayout, strings.TrimSpace(startStr))
+	if err != nil {
+		return nil, fmt.Errorf("invalid start of window %s: %w", s, err)
+	}
+
+	end, err := time.Parse(windowTimeLayout, strings.TrimSpace(endStr))
+	if err != nil {
+		return nil, fmt.Errorf("invalid end of window %s: %w", s, err)
+	}
+
+	window := Window{
+		Start: time.Duration(start.Hour())*time.Hour + time.Duration(start.Minute())*time.Minute,
+		End:   time.Duration(end.Hour())*time.Hour + time.Duration(end.Minute())*time.Minute,
+	}
+
+	if window.Start == window.End {
+		return nil, fmt.Errorf("window cannot be empty: %s", s)
+	}
+
+	return &window, nil
+}
+
+func (w *Window) String() string {
+	return formatOffset(w.Start) + "-" + formatOffset(w.End)
+}
+
+// Contains returns true if the time is within the window.
+func (w *Window) Contains(t time.Time) bool {
+	offset := sinceMidnight(t)
+
+	if w.Start < w.End {
+		return offset >= w.Start && offset < w.End
+	}
+
+	return offset >= w.Start || offset < w.End
+}
+
+// NextStart returns the first start of the window after the time.
+func (w *Window) NextStart(t time.Time) time.Time {
+	return nextOffset(t, w.Start)
+}
+
+// NextEnd returns the first end of the window after the time.
+func (w *Window) NextEnd(t time.Time) time.Time {
+	return nextOffset(t, w.End)
+}
+
+func sinceMidnight(t time.Time) time.Duration {
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second + time.Duration(t.Nanosecond())
+}
+
+// nextOffset returns the first time after t with the given offset from midnight.
+// It is computed on the calendar, so that it is correct on the days of daylight saving time changes.
+func nextOffset(t time.Time, offset time.Duration) time.Time {
+	hour, minute := int(offset/time.Hour), int(offset%time.Hour/time.Minute)
+
+	next := time.Date(t.Year(), t.Month(), t.Day(), hour, minute, 0, 0, t.Location())
+	if !next.After(t) {
+		next = time.Date(t.Year(), t.Month(), t.Day()+1, hour, minute, 0, 0, t.Location())
+	}
+
+	return next
+}
+
+func formatOffset(offset time.Duration) string {
+	return time.Date(0, 1, 1, 0, 0, 0, 0, time.UTC).Add(offset % day).Format(windowTimeLayout)
+}
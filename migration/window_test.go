@@ -0,0 +1,52 @@
+package migration_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/utkuozdemir/pv-migrate/migration"
+)
+
+func TestParseWindow(t *testing.T) {
+	t.Parallel()
+
+	window, err := migration.ParseWindow("22:00-06:30")
+	require.NoError(t, err)
+	assert.Equal(t, &migration.Window{Start: 22 * time.Hour, End: 6*time.Hour + 30*time.Minute}, window)
+	assert.Equal(t, "22:00-06:30", window.String())
+
+	for _, invalid := range []string{"", "22:00", "22-06", "25:00-06:00", "22:00-22:00", "22:00-06:00-08:00"} {
+		_, err = migration.ParseWindow(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestWindow(t *testing.T) {
+	t.Parallel()
+
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, time.March, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	overnight := migration.Window{Start: 22 * time.Hour, End: 6 * time.Hour}
+
+	assert.True(t, overnight.Contains(at(1, 23, 0)))
+	assert.True(t, overnight.Contains(at(1, 22, 0)))
+	assert.True(t, overnight.Contains(at(1, 5, 59)))
+	assert.False(t, overnight.Contains(at(1, 6, 0)))
+	assert.False(t, overnight.Contains(at(1, 12, 0)))
+	assert.Equal(t, at(1, 22, 0), overnight.NextStart(at(1, 12, 0)))
+	assert.Equal(t, at(2, 22, 0), overnight.NextStart(at(1, 22, 0)))
+	assert.Equal(t, at(2, 6, 0), overnight.NextEnd(at(1, 23, 0)))
+	assert.Equal(t, at(1, 6, 0), overnight.NextEnd(at(1, 1, 0)))
+
+	daytime := migration.Window{Start: 9 * time.Hour, End: 17 * time.Hour}
+
+	assert.True(t, daytime.Contains(at(1, 9, 0)))
+	assert.False(t, daytime.Contains(at(1, 17, 0)))
+	assert.False(t, daytime.Contains(at(1, 8, 59)))
+	assert.Equal(t, at(2, 9, 0), daytime.NextStart(at(1, 17, 0)))
+}
@@ -0,0 +1,46 @@
+package migrator
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/utkuozdemir/pv-migrate/migration"
+	"github.com/utkuozdemir/pv-migrate/strategy"
+)
+
+// Archive writes the data of the source PVC of the request into an archive file at the given path.
+func (m *Migrator) Archive(ctx context.Context, request *migration.Request, outputPath, compression string,
+	logger *slog.Logger,
+) error {
+	logger = logger.With("source", request.Source.Namespace+"/"+request.Source.Name, "dest", outputPath)
+
+	sourceInfo, err := m.buildLocalDirPVCInfo(ctx, request, request.Source, logger)
+	if err != nil {
+		return err
+	}
+
+	return m.runLocalDir(ctx, request, sourceInfo, nil, logger, func(attempt *migration.Attempt) error {
+		return strategy.Archive(ctx, attempt, outputPath, compression, logger)
+	})
+}
+
+// Restore extracts the data in the archive file at the given path into the destination PVC of the request.
+func (m *Migrator) Restore(ctx context.Context, request *migration.Request, inputPath string,
+	logger *slog.Logger,
+) error {
+	logger = logger.With("source", inputPath, "dest", request.Dest.Namespace+"/"+request.Dest.Name)
+
+	destInfo, err := m.buildLocalDirPVCInfo(ctx, request, request.Dest, logger)
+	if err != nil {
+		return err
+	}
+
+	if !(destInfo.SupportsRWO || destInfo.SupportsRWX) {
+		return errors.New("destination PVC is not writable")
+	}
+
+	return m.runLocalDir(ctx, request, nil, destInfo, logger, func(attempt *migration.Attempt) error {
+		return strategy.Restore(ctx, attempt, inputPath, logger)
+	})
+}
@@ -0,0 +1,68 @@
+package migrator
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/utkuozdemir/pv-migrate/migration"
+	"github.com/utkuozdemir/pv-migrate/strategy"
+)
+
+type rankedStrategy struct {
+	name  string
+	score strategy.Score
+}
+
+// isAuto returns whether the strategies of the request are to be selected automatically.
+func isAuto(strategies []string) bool {
+	return len(strategies) == 1 && strategies[0] == strategy.AutoStrategy
+}
+
+// rankStrategies scores the given strategies for the migration and returns the names of the feasible ones,
+// ordered by their scores, highest first.
+func rankStrategies(ctx context.Context, mig *migration.Migration,
+	nameToStrategyMap map[string]strategy.Strategy, logger *slog.Logger,
+) []string {
+	facts := strategy.GatherFacts(ctx, mig, logger)
+	ranking := make([]rankedStrategy, 0, len(nameToStrategyMap))
+
+	for _, name := range slices.Sorted(maps.Keys(nameToStrategyMap)) {
+		score := strategy.Score{
+			Value:   strategy.UnscoredValue,
+			Reasons: []string{"the strategy cannot estimate its feasibility"},
+		}
+
+		if scorer, ok := nameToStrategyMap[name].(strategy.Scorer); ok {
+			score = scorer.Score(mig, facts)
+		}
+
+		ranking = append(ranking, rankedStrategy{name: name, score: score})
+	}
+
+	sort.SliceStable(ranking, func(i, j int) bool {
+		return ranking[i].score.Value > ranking[j].score.Value
+	})
+
+	names := make([]string, 0, len(ranking))
+
+	for _, ranked := range ranking {
+		reasons := strings.Join(ranked.score.Reasons, "; ")
+
+		if ranked.score.Value <= 0 {
+			logger.Info("🚫 Strategy is not feasible", "strategy", ranked.name, "reasons", reasons)
+
+			continue
+		}
+
+		names = append(names, ranked.name)
+
+		logger.Info("🏅 Strategy ranked", "rank", len(names), "strategy", ranked.name,
+			"score", ranked.score.Value, "reasons", reasons)
+	}
+
+	return names
+}
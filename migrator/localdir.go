@@ -0,0 +1,104 @@
+package migrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/utkuozdemir/pv-migrate/helm"
+	"github.com/utkuozdemir/pv-migrate/migration"
+	"github.com/utkuozdemir/pv-migrate/pvc"
+	"github.com/utkuozdemir/pv-migrate/strategy"
+	"github.com/utkuozdemir/pv-migrate/util"
+)
+
+// Export copies the data of the source PVC of the request into the given local directory.
+func (m *Migrator) Export(ctx context.Context, request *migration.Request, localDir string,
+	logger *slog.Logger,
+) error {
+	logger = logger.With("source", request.Source.Namespace+"/"+request.Source.Name, "dest", localDir)
+
+	sourceInfo, err := m.buildLocalDirPVCInfo(ctx, request, request.Source, logger)
+	if err != nil {
+		return err
+	}
+
+	return m.runLocalDir(ctx, request, sourceInfo, nil, logger, func(attempt *migration.Attempt) error {
+		return strategy.Export(ctx, attempt, localDir, logger)
+	})
+}
+
+// Import copies the data in the given local directory into the destination PVC of the request.
+func (m *Migrator) Import(ctx context.Context, request *migration.Request, localDir string,
+	logger *slog.Logger,
+) error {
+	logger = logger.With("source", localDir, "dest", request.Dest.Namespace+"/"+request.Dest.Name)
+
+	destInfo, err := m.buildLocalDirPVCInfo(ctx, request, request.Dest, logger)
+	if err != nil {
+		return err
+	}
+
+	if !(destInfo.SupportsRWO || destInfo.SupportsRWX) {
+		return errors.New("destination PVC is not writable")
+	}
+
+	return m.runLocalDir(ctx, request, nil, destInfo, logger, func(attempt *migration.Attempt) error {
+		return strategy.Import(ctx, attempt, localDir, logger)
+	})
+}
+
+func (m *Migrator) runLocalDir(ctx context.Context, request *migration.Request, sourceInfo, destInfo *pvc.Info,
+	logger *slog.Logger, run func(attempt *migration.Attempt) error,
+) error {
+	chart, err := helm.LoadChart()
+	if err != nil {
+		return fmt.Errorf("failed to load helm chart: %w", err)
+	}
+
+	attemptID := util.RandomHexadecimalString(attemptIDLength)
+	attempt := migration.Attempt{
+		ID:                    attemptID,
+		HelmReleaseNamePrefix: "pv-migrate-" + attemptID,
+		Migration: &migration.Migration{
+			Chart:      chart,
+			Request:    request,
+			SourceInfo: sourceInfo,
+			DestInfo:   destInfo,
+		},
+	}
+
+	if err = run(&attempt); err != nil {
+		return err
+	}
+
+	logger.Info("✅ Transfer succeeded")
+
+	return nil
+}
+
+func (m *Migrator) buildLocalDirPVCInfo(ctx context.Context, request *migration.Request,
+	info *migration.PVCInfo, logger *slog.Logger,
+) (*pvc.Info, error) {
+	client, err := m.getKubeClient(info.KubeconfigPath, info.Context, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	namespace := info.Namespace
+	if namespace == "" {
+		namespace = client.NsInContext
+	}
+
+	pvcInfo, err := pvc.New(ctx, client, namespace, info.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get PVC info: %w", err)
+	}
+
+	if err = handleMounted(pvcInfo, request.IgnoreMounted, logger); err != nil {
+		return nil, err
+	}
+
+	return pvcInfo, nil
+}
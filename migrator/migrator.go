@@ -5,13 +5,16 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/utkuozdemir/pv-migrate/helm"
 	"github.com/utkuozdemir/pv-migrate/k8s"
 	"github.com/utkuozdemir/pv-migrate/migration"
 	"github.com/utkuozdemir/pv-migrate/pvc"
 	"github.com/utkuozdemir/pv-migrate/strategy"
+	"github.com/utkuozdemir/pv-migrate/transfer"
 	"github.com/utkuozdemir/pv-migrate/util"
 )
 
@@ -24,6 +27,13 @@ type (
 	clusterClientGetter func(kubeconfigPath, context string, logger *slog.Logger) (*k8s.ClusterClient, error)
 )
 
+// attemptResult is the outcome of a single attempt of the migration with a strategy.
+type attemptResult struct {
+	id       string
+	strategy string
+	err      error
+}
+
 type Migrator struct {
 	getKubeClient  clusterClientGetter
 	getStrategyMap strategyMapGetter
@@ -38,11 +48,22 @@ func New() *Migrator {
 }
 
 func (m *Migrator) Run(ctx context.Context, request *migration.Request, logger *slog.Logger) error {
-	nameToStrategyMap, err := m.getStrategyMap(request.Strategies)
+	strategies := request.Strategies
+
+	auto := isAuto(strategies)
+	if auto {
+		strategies = strategy.AllStrategies
+	}
+
+	nameToStrategyMap, err := m.getStrategyMap(strategies)
 	if err != nil {
 		return err
 	}
 
+	if _, err = transfer.Get(request.Engine); err != nil {
+		return fmt.Errorf("invalid engine: %w", err)
+	}
+
 	logger = logger.With("source", request.Source.Namespace+"/"+request.Source.Name,
 		"dest", request.Dest.Namespace+"/"+request.Dest.Name)
 
@@ -51,42 +72,92 @@ func (m *Migrator) Run(ctx context.Context, request *migration.Request, logger *
 		return err
 	}
 
-	logger.Info("💭 Attempting migration", "strategies", strings.Join(request.Strategies, ","))
-
-	for _, name := range request.Strategies {
-		attemptID := util.RandomHexadecimalString(attemptIDLength)
+	if auto {
+		if strategies = rankStrategies(ctx, mig, nameToStrategyMap, logger); len(strategies) == 0 {
+			return errors.New("no feasible strategy found for this migration")
+		}
+	}
 
-		attemptLogger := logger.With("attempt_id", attemptID, "strategy", name)
+	logger.Info("💭 Attempting migration", "strategies", strings.Join(strategies, ","))
 
-		attemptLogger.Info("🚁 Attempt using strategy")
+	var results []attemptResult
 
-		attempt := migration.Attempt{
-			ID:                    attemptID,
-			HelmReleaseNamePrefix: "pv-migrate-" + attemptID,
-			Migration:             mig,
-		}
+	for _, name := range strategies {
+		policy := policyFor(request, name)
 
-		s := nameToStrategyMap[name]
+		for retry := 0; ; retry++ {
+			result := runAttempt(ctx, mig, name, nameToStrategyMap[name], policy, logger)
+			results = append(results, result)
 
-		if runErr := s.Run(ctx, &attempt, attemptLogger); runErr != nil {
-			if errors.Is(err, strategy.ErrUnaccepted) {
-				attemptLogger.Info("🦊 This strategy cannot handle this migration, will try the next one")
+			if result.err == nil {
+				return nil
+			}
 
-				continue
+			if retry >= policy.Retries || !isRetryable(result.err) || ctx.Err() != nil {
+				break
 			}
 
-			attemptLogger.Warn("🔶 Migration failed with this strategy, "+
-				"will try with the remaining strategies", "error", runErr)
+			backoff := util.Backoff(policy.RetryBackoff, retry+1)
+
+			logger.Info("🔁 Retrying the strategy", "strategy", name,
+				"retry", fmt.Sprintf("%d/%d", retry+1, policy.Retries), "backoff", backoff)
 
-			continue
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("migration cancelled: %w", ctx.Err())
+			case <-time.After(backoff):
+			}
 		}
+	}
 
-		attemptLogger.Info("✅ Migration succeeded")
+	logAttemptSummary(results, logger)
 
-		return nil
+	return errors.New("all strategies failed for this migration")
+}
+
+// runAttempt runs a single attempt of the migration with the strategy, enforcing the deadline of the policy.
+func runAttempt(ctx context.Context, mig *migration.Migration, name string, strat strategy.Strategy,
+	policy migration.StrategyPolicy, logger *slog.Logger,
+) attemptResult {
+	attemptID := util.RandomHexadecimalString(attemptIDLength)
+
+	attemptLogger := logger.With("attempt_id", attemptID, "strategy", name)
+
+	attemptLogger.Info("🚁 Attempt using strategy")
+
+	attempt := migration.Attempt{
+		ID:                    attemptID,
+		HelmReleaseNamePrefix: "pv-migrate-" + attemptID,
+		Migration:             mig,
+		InstallTimeout:        policy.InstallTimeout,
 	}
 
-	return errors.New("all strategies failed for this migration")
+	attemptCtx := ctx
+
+	if policy.Timeout > 0 {
+		var cancel context.CancelFunc
+
+		attemptCtx, cancel = context.WithTimeout(ctx, policy.Timeout)
+		defer cancel()
+	}
+
+	runErr := strat.Run(attemptCtx, &attempt, attemptLogger)
+	if runErr != nil && ctx.Err() == nil && errors.Is(attemptCtx.Err(), context.DeadlineExceeded) {
+		runErr = fmt.Errorf("attempt timed out after %s: %w", policy.Timeout, runErr)
+	}
+
+	switch {
+	case runErr == nil:
+		attemptLogger.Info("✅ Migration succeeded")
+	case errors.Is(runErr, strategy.ErrUnaccepted):
+		attemptLogger.Info("🦊 This strategy cannot handle this migration, will try the next one")
+	case errors.Is(runErr, strategy.ErrUnreachable):
+		attemptLogger.Warn("📵 The source is unreachable with this strategy", "error", runErr)
+	default:
+		attemptLogger.Warn("🔶 Migration failed with this strategy", "error", runErr)
+	}
+
+	return attemptResult{id: attemptID, strategy: name, err: runErr}
 }
 
 func (m *Migrator) buildMigration(ctx context.Context, request *migration.Request,
@@ -134,6 +205,10 @@ func (m *Migrator) buildMigration(ctx context.Context, request *migration.Reques
 		return nil, errors.New("destination PVC is not writable")
 	}
 
+	if err = matchDestFSGroup(ctx, request, destPvcInfo, logger); err != nil {
+		return nil, err
+	}
+
 	mig := migration.Migration{
 		Chart:      chart,
 		Request:    request,
@@ -166,6 +241,27 @@ func (m *Migrator) getClusterClients(r *migration.Request,
 	return sourceClient, destClient, nil
 }
 
+// matchDestFSGroup maps all the groups of the files to the fsGroup of the workload using the destination PVC,
+// if it is requested.
+func matchDestFSGroup(ctx context.Context, request *migration.Request, destInfo *pvc.Info,
+	logger *slog.Logger,
+) error {
+	if !request.MatchDestFSGroup {
+		return nil
+	}
+
+	fsGroup, workload, err := pvc.FindFSGroup(ctx, destInfo.ClusterClient.KubeClient, destInfo.Claim)
+	if err != nil {
+		return fmt.Errorf("failed to find the fsGroup of the destination: %w", err)
+	}
+
+	logger.Info("👥 Matching the fsGroup of the destination workload", "workload", workload, "fs_group", fsGroup)
+
+	request.Ownership.GroupMap = []string{"*:" + strconv.FormatInt(fsGroup, 10)}
+
+	return nil
+}
+
 func handleMountedPVCs(r *migration.Request, sourcePvcInfo, destPvcInfo *pvc.Info, logger *slog.Logger) error {
 	ignoreMounted := r.IgnoreMounted
 
@@ -197,3 +293,21 @@ func handleMounted(info *pvc.Info, ignoreMounted bool, logger *slog.Logger) erro
 	return fmt.Errorf("PVC is mounted to a node and --ignore-mounted is not requested: "+
 		"node: %s claim %s", info.MountedNode, info.Claim.Name)
 }
+
+// logAttemptSummary logs the outcome of each failed attempt, so that the reasons can be seen together.
+func logAttemptSummary(results []attemptResult, logger *slog.Logger) {
+	logger.Info("📋 Attempt summary")
+
+	for _, result := range results {
+		attemptLogger := logger.With("attempt_id", result.id, "strategy", result.strategy)
+
+		switch {
+		case errors.Is(result.err, strategy.ErrUnaccepted):
+			attemptLogger.Info("📋 Attempt", "outcome", "unaccepted")
+		case errors.Is(result.err, strategy.ErrUnreachable):
+			attemptLogger.Info("📋 Attempt", "outcome", "unreachable", "error", result.err)
+		default:
+			attemptLogger.Info("📋 Attempt", "outcome", "failed", "error", result.err)
+		}
+	}
+}
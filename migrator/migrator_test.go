@@ -2,8 +2,11 @@ package migrator
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 	"testing"
+	"time"
 
 	"github.com/neilotoole/slogt"
 	"github.com/stretchr/testify/assert"
@@ -74,6 +77,22 @@ func TestBuildTaskMounted(t *testing.T) {
 	require.Error(t, err)
 }
 
+func TestBuildTaskMatchDestFSGroup(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	logger := slogt.New(t)
+
+	m := Migrator{getKubeClient: fakeClusterClientGetter()}
+	request := buildMigration(true)
+	request.MatchDestFSGroup = true
+
+	_, err := m.buildMigration(ctx, request, logger)
+	require.ErrorContains(t, err, "no pod or workload using pvc namespace2/pvc2 sets an fsGroup")
+}
+
 func TestRunStrategiesInOrder(t *testing.T) {
 	t.Parallel()
 
@@ -127,6 +146,188 @@ func TestRunStrategiesInOrder(t *testing.T) {
 	assert.Equal(t, []int{3, 1, 2}, result)
 }
 
+func TestRunStrategiesUnreachable(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	logger := slogt.New(t)
+
+	var result []int
+
+	str1 := mockStrategy{
+		runFunc: func(_ context.Context, _ *migration.Attempt) error {
+			result = append(result, 1)
+
+			return fmt.Errorf("%w: sshd:22: no SSH handshake within 10s", strategy.ErrUnreachable)
+		},
+	}
+
+	str2 := mockStrategy{
+		runFunc: func(_ context.Context, _ *migration.Attempt) error {
+			result = append(result, 2)
+
+			return errors.New("transfer failed")
+		},
+	}
+
+	migrator := Migrator{
+		getKubeClient: fakeClusterClientGetter(),
+		getStrategyMap: func([]string) (map[string]strategy.Strategy, error) {
+			return map[string]strategy.Strategy{
+				"str1": &str1,
+				"str2": &str2,
+			}, nil
+		},
+	}
+
+	mig := buildMigrationRequestWithStrategies([]string{"str1", "str2"}, true)
+
+	err := migrator.Run(ctx, mig, logger)
+	require.Error(t, err)
+	assert.Equal(t, []int{1, 2}, result)
+}
+
+func TestRunStrategiesRetries(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	logger := slogt.New(t)
+
+	var (
+		result     []string
+		flakyCalls int
+	)
+
+	flaky := mockStrategy{
+		runFunc: func(_ context.Context, _ *migration.Attempt) error {
+			result = append(result, "flaky")
+
+			if flakyCalls++; flakyCalls < 3 {
+				return errors.New("transient failure")
+			}
+
+			return nil
+		},
+	}
+
+	unreachable := mockStrategy{
+		runFunc: func(_ context.Context, _ *migration.Attempt) error {
+			result = append(result, "unreachable")
+
+			return strategy.ErrUnreachable
+		},
+	}
+
+	migrator := Migrator{
+		getKubeClient: fakeClusterClientGetter(),
+		getStrategyMap: func([]string) (map[string]strategy.Strategy, error) {
+			return map[string]strategy.Strategy{
+				"flaky":       &flaky,
+				"unreachable": &unreachable,
+			}, nil
+		},
+	}
+
+	mig := buildMigrationRequestWithStrategies([]string{"unreachable", "flaky"}, true)
+	mig.StrategyPolicies = map[string]migration.StrategyPolicy{
+		"unreachable": {Retries: 3, RetryBackoff: time.Millisecond},
+		"flaky":       {Retries: 2, RetryBackoff: time.Millisecond},
+	}
+
+	err := migrator.Run(ctx, mig, logger)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"unreachable", "flaky", "flaky", "flaky"}, result)
+}
+
+func TestRunStrategiesTimeout(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	logger := slogt.New(t)
+
+	var installTimeouts []time.Duration
+
+	slow := mockStrategy{
+		runFunc: func(ctx context.Context, attempt *migration.Attempt) error {
+			installTimeouts = append(installTimeouts, attempt.InstallTimeout)
+
+			<-ctx.Done()
+
+			return ctx.Err()
+		},
+	}
+
+	migrator := Migrator{
+		getKubeClient: fakeClusterClientGetter(),
+		getStrategyMap: func([]string) (map[string]strategy.Strategy, error) {
+			return map[string]strategy.Strategy{strategy.LbSvcStrategy: &slow}, nil
+		},
+	}
+
+	mig := buildMigrationRequestWithStrategies([]string{strategy.LbSvcStrategy}, true)
+	mig.HelmTimeout = time.Minute
+	mig.LBSvcTimeout = 2 * time.Minute
+	mig.StrategyPolicies = map[string]migration.StrategyPolicy{
+		strategy.LbSvcStrategy: {Timeout: 10 * time.Millisecond, Retries: 1, RetryBackoff: time.Millisecond},
+	}
+
+	err := migrator.Run(ctx, mig, logger)
+	require.Error(t, err)
+	assert.Equal(t, []time.Duration{2 * time.Minute, 2 * time.Minute}, installTimeouts)
+}
+
+func TestRunStrategiesAuto(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	logger := slogt.New(t)
+
+	var result []string
+
+	buildStrategy := func(name string, score int) *mockScoredStrategy {
+		return &mockScoredStrategy{
+			mockStrategy: mockStrategy{
+				runFunc: func(_ context.Context, _ *migration.Attempt) error {
+					result = append(result, name)
+
+					return strategy.ErrUnaccepted
+				},
+			},
+			score: strategy.Score{Value: score},
+		}
+	}
+
+	migrator := Migrator{
+		getKubeClient: fakeClusterClientGetter(),
+		getStrategyMap: func([]string) (map[string]strategy.Strategy, error) {
+			return map[string]strategy.Strategy{
+				"slow":       buildStrategy("slow", 10),
+				"infeasible": buildStrategy("infeasible", 0),
+				"fast":       buildStrategy("fast", 90),
+				"unscored": &mockStrategy{runFunc: func(context.Context, *migration.Attempt) error {
+					result = append(result, "unscored")
+
+					return strategy.ErrUnaccepted
+				}},
+			}, nil
+		},
+	}
+
+	mig := buildMigrationRequestWithStrategies([]string{strategy.AutoStrategy}, true)
+
+	err := migrator.Run(ctx, mig, logger)
+	require.Error(t, err)
+	assert.Equal(t, []string{"fast", "unscored", "slow"}, result)
+}
+
 func buildMigration(ignoreMounted bool) *migration.Request {
 	return buildMigrationRequestWithStrategies(strategy.DefaultStrategies, ignoreMounted)
 }
@@ -204,3 +405,12 @@ type mockStrategy struct {
 func (m *mockStrategy) Run(ctx context.Context, attempt *migration.Attempt, _ *slog.Logger) error {
 	return m.runFunc(ctx, attempt)
 }
+
+type mockScoredStrategy struct {
+	mockStrategy
+	score strategy.Score
+}
+
+func (m *mockScoredStrategy) Score(*migration.Migration, *strategy.Facts) strategy.Score {
+	return m.score
+}
@@ -0,0 +1,49 @@
+package migrator
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/utkuozdemir/pv-migrate/migration"
+	"github.com/utkuozdemir/pv-migrate/strategy"
+)
+
+const (
+	defaultRetryBackoff = 10 * time.Second
+)
+
+// policyFor returns the policy of the strategy in the request, with the defaults filled in.
+func policyFor(request *migration.Request, name string) migration.StrategyPolicy {
+	policy := request.StrategyPolicies[name]
+
+	if policy.InstallTimeout == 0 {
+		policy.InstallTimeout = request.HelmTimeout
+
+		// the installation waits for the load balancer service to be assigned an address
+		if name == strategy.LbSvcStrategy {
+			policy.InstallTimeout = max(request.HelmTimeout, request.LBSvcTimeout)
+		}
+	}
+
+	if policy.RetryBackoff == 0 {
+		policy.RetryBackoff = defaultRetryBackoff
+	}
+
+	return policy
+}
+
+// isRetryable returns whether the failed attempt might succeed when retried with the same strategy.
+func isRetryable(err error) bool {
+	if errors.Is(err, strategy.ErrUnaccepted) || errors.Is(err, strategy.ErrUnreachable) ||
+		errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var transferErr *strategy.TransferError
+	if errors.As(err, &transferErr) {
+		return transferErr.Status.Retryable
+	}
+
+	return true
+}
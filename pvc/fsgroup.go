@@ -0,0 +1,131 @@
+package pvc
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// workloadFSGroup is the fsGroup of a pod or a workload, e.g. Deployment/app.
+type workloadFSGroup struct {
+	workload string
+	fsGroup  int64
+}
+
+// FindFSGroup returns the fsGroup of the pods and the workloads using the claim, and the kind and name of
+// the one it was found on, e.g. StatefulSet/db. The workloads are checked as well, so that it is found
+// while they are scaled down for the migration.
+//
+// It fails if none of them sets an fsGroup, or they set different ones.
+func FindFSGroup(ctx context.Context, kubeClient kubernetes.Interface,
+	claim *corev1.PersistentVolumeClaim,
+) (int64, string, error) {
+	found, err := listFSGroups(ctx, kubeClient, claim)
+	if err != nil {
+		return 0, "", err
+	}
+
+	if len(found) == 0 {
+		return 0, "", fmt.Errorf("no pod or workload using pvc %s/%s sets an fsGroup", claim.Namespace, claim.Name)
+	}
+
+	for _, other := range found[1:] {
+		if other.fsGroup != found[0].fsGroup {
+			return 0, "", fmt.Errorf("the workloads using pvc %s/%s set different fsGroups: %s has %d, %s has %d",
+				claim.Namespace, claim.Name, found[0].workload, found[0].fsGroup, other.workload, other.fsGroup)
+		}
+	}
+
+	return found[0].fsGroup, found[0].workload, nil
+}
+
+func listFSGroups(ctx context.Context, kubeClient kubernetes.Interface,
+	claim *corev1.PersistentVolumeClaim,
+) ([]workloadFSGroup, error) {
+	namespace := claim.Namespace
+
+	var found []workloadFSGroup
+
+	add := func(kind, name string, spec *corev1.PodSpec, usesClaim bool) {
+		if !usesClaim || spec.SecurityContext == nil || spec.SecurityContext.FSGroup == nil {
+			return
+		}
+
+		found = append(found, workloadFSGroup{workload: kind + "/" + name, fsGroup: *spec.SecurityContext.FSGroup})
+	}
+
+	pods, err := kubeClient.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+
+	for _, pod := range pods.Items {
+		add("Pod", pod.Name, &pod.Spec, mountsClaim(&pod.Spec, claim.Name))
+	}
+
+	deployments, err := kubeClient.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deployments: %w", err)
+	}
+
+	for _, deployment := range deployments.Items {
+		spec := &deployment.Spec.Template.Spec
+		add("Deployment", deployment.Name, spec, mountsClaim(spec, claim.Name))
+	}
+
+	statefulSets, err := kubeClient.AppsV1().StatefulSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list statefulsets: %w", err)
+	}
+
+	for _, statefulSet := range statefulSets.Items {
+		spec := &statefulSet.Spec.Template.Spec
+		add("StatefulSet", statefulSet.Name, spec,
+			mountsClaim(spec, claim.Name) || ownsClaim(&statefulSet, claim.Name))
+	}
+
+	daemonSets, err := kubeClient.AppsV1().DaemonSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list daemonsets: %w", err)
+	}
+
+	for _, daemonSet := range daemonSets.Items {
+		spec := &daemonSet.Spec.Template.Spec
+		add("DaemonSet", daemonSet.Name, spec, mountsClaim(spec, claim.Name))
+	}
+
+	return found, nil
+}
+
+func mountsClaim(spec *corev1.PodSpec, claimName string) bool {
+	for _, volume := range spec.Volumes {
+		if volume.PersistentVolumeClaim != nil && volume.PersistentVolumeClaim.ClaimName == claimName {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ownsClaim returns true if the claim is created from one of the volume claim templates of the statefulset,
+// i.e. it is named <template>-<statefulset>-<ordinal>.
+func ownsClaim(statefulSet *appsv1.StatefulSet, claimName string) bool {
+	for _, template := range statefulSet.Spec.VolumeClaimTemplates {
+		ordinal, ok := strings.CutPrefix(claimName, template.Name+"-"+statefulSet.Name+"-")
+		if !ok {
+			continue
+		}
+
+		if _, err := strconv.Atoi(ordinal); err == nil {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,76 @@
+package pvc_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/utkuozdemir/pv-migrate/pvc"
+)
+
+func TestFindFSGroup(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	claim := corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "data-db-0", Namespace: "ns"}}
+
+	statefulSet := appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "ns"},
+		Spec: appsv1.StatefulSetSpec{
+			Template:             buildTestPodTemplate("", 1000),
+			VolumeClaimTemplates: []corev1.PersistentVolumeClaim{{ObjectMeta: metav1.ObjectMeta{Name: "data"}}},
+		},
+	}
+
+	fsGroup, workload, err := pvc.FindFSGroup(ctx, fake.NewSimpleClientset(&statefulSet), &claim)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1000), fsGroup)
+	assert.Equal(t, "StatefulSet/db", workload)
+
+	deployment := appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "backup", Namespace: "ns"},
+		Spec:       appsv1.DeploymentSpec{Template: buildTestPodTemplate("data-db-0", 2000)},
+	}
+
+	_, _, err = pvc.FindFSGroup(ctx, fake.NewSimpleClientset(&statefulSet, &deployment), &claim)
+	require.ErrorContains(t, err, "different fsGroups")
+
+	other := corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "data-db-x", Namespace: "ns"}}
+
+	_, _, err = pvc.FindFSGroup(ctx, fake.NewSimpleClientset(&statefulSet, &deployment), &other)
+	require.ErrorContains(t, err, "no pod or workload")
+
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "backup-abcde", Namespace: "ns"},
+		Spec:       buildTestPodTemplate("data-db-0", 1000).Spec,
+	}
+
+	fsGroup, _, err = pvc.FindFSGroup(ctx, fake.NewSimpleClientset(&statefulSet, &pod), &claim)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1000), fsGroup)
+}
+
+func buildTestPodTemplate(claimName string, fsGroup int64) corev1.PodTemplateSpec {
+	template := corev1.PodTemplateSpec{
+		Spec: corev1.PodSpec{SecurityContext: &corev1.PodSecurityContext{FSGroup: &fsGroup}},
+	}
+
+	if claimName != "" {
+		template.Spec.Volumes = []corev1.Volume{{
+			Name: "data",
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: claimName},
+			},
+		}}
+	}
+
+	return template
+}
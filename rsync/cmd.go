@@ -3,29 +3,115 @@ package rsync
 import (
 	"errors"
 	"fmt"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+
+	"github.com/utkuozdemir/pv-migrate/rsync/progress"
 )
 
+// vanishedExitCode is the exit code of rsync for "partial transfer due to vanished source files".
+const vanishedExitCode = 24
+
+var bwLimitRegex = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?[KMGkmg]?$`)
+
 type Cmd struct {
-	Port        int
-	NoChown     bool
-	Delete      bool
-	SrcUseSSH   bool
-	DestUseSSH  bool
-	Command     string
-	SrcSSHUser  string
-	SrcSSHHost  string
-	SrcPath     string
-	DestSSHUser string
-	DestSSHHost string
-	DestPath    string
-	Compress    bool
+	Port            int
+	NoChown         bool
+	Delete          bool
+	SrcUseSSH       bool
+	DestUseSSH      bool
+	Command         string
+	SrcSSHUser      string
+	SrcSSHHost      string
+	SrcPath         string
+	DestSSHUser     string
+	DestSSHHost     string
+	DestPath        string
+	Compress        bool
+	SSHIdentityFile string
+	// SSHProxyCommand is the command to connect to the remote through, see ProxyCommand in ssh_config(5).
+	SSHProxyCommand string
+	SSHOptions      SSHOptions
+	Filter          Filter
+	Preserve        Preserve
+	LargeFile       LargeFile
+	// Ownership changes the owners of the files on the destination. It requires the owners to be preserved,
+	// i.e. NoChown to be unset.
+	Ownership Ownership
+	// BwLimit is the maximum transfer rate, e.g. 10M or 500K, in KiB/s if it has no suffix. Unlimited if empty.
+	BwLimit string
+	// Parallel is the number of rsync processes to run concurrently, each copying a part of the top-level entries
+	// of the source. A single process is run if it is less than 2.
+	Parallel int
+	// AllowVanished makes the command exit successfully if only some source files vanished during the transfer,
+	// e.g. on live sources.
+	AllowVanished bool
+	// ItemizeChanges outputs the changes of the files on the destination in progress.ChangeOutFormat.
+	ItemizeChanges bool
+	// ExtraArgs are additional options of rsync, added after the ones set by pv-migrate.
+	ExtraArgs []string
 }
 
+// Build returns the shell command which runs the transfer, with every argument of rsync quoted,
+// so that the paths can contain any character.
 func (c *Cmd) Build() (string, error) {
+	cmd, sshCmd, rsyncArgs, err := c.prepare()
+	if err != nil {
+		return "", err
+	}
+
+	src := c.buildSrc()
+	dest := c.buildDest()
+
+	if c.Parallel > 1 {
+		return c.capabilityCheck(cmd, sshCmd) + c.buildParallel(cmd, sshCmd, rsyncArgs, src, dest), nil
+	}
+
+	argv := slices.Concat([]string{cmd}, rsyncArgs, c.Filter.Args(), []string{src, dest})
+
+	return c.capabilityCheck(cmd, sshCmd) + c.invocation(shellJoin(argv)), nil
+}
+
+// capabilityCheck returns the shell commands checking that both the local rsync and the one on the remote,
+// e.g. in the sshd image, support the preserve options.
+func (c *Cmd) capabilityCheck(cmd, sshCmd string) string {
+	check := c.Preserve.capabilityCheck(shellJoin([]string{cmd, "--version"}), "rsync in the image")
+
+	var remote string
+
+	switch {
+	case c.SrcUseSSH:
+		remote = sshRemote(c.SrcSSHUser, c.SrcSSHHost)
+	case c.DestUseSSH:
+		remote = sshRemote(c.DestSSHUser, c.DestSSHHost)
+	default:
+		return check
+	}
+
+	// the remote shell command of rsync is split by the shell the same way as by rsync
+	return check + c.Preserve.capabilityCheck(sshCmd+" "+shellQuote(remote)+" rsync --version",
+		"rsync on "+remote)
+}
+
+// Args returns the arguments of the rsync process which runs the transfer, starting with the rsync command,
+// without the shell commands Build wraps it with, i.e. the capability check, the parallel streams
+// and the handling of the vanished files.
+func (c *Cmd) Args() ([]string, error) {
+	cmd, _, rsyncArgs, err := c.prepare()
+	if err != nil {
+		return nil, err
+	}
+
+	return slices.Concat([]string{cmd}, rsyncArgs, c.Filter.Args(), []string{c.buildSrc(), c.buildDest()}), nil
+}
+
+// prepare validates the command, and returns the rsync command, its remote shell command,
+// and its arguments before the filter and the paths.
+func (c *Cmd) prepare() (string, string, []string, error) {
 	if c.SrcUseSSH && c.DestUseSSH {
-		return "", errors.New("cannot use ssh on both source and destination")
+		return "", "", nil, errors.New("cannot use ssh on both source and destination")
 	}
 
 	cmd := "rsync"
@@ -33,19 +119,64 @@ func (c *Cmd) Build() (string, error) {
 		cmd = c.Command
 	}
 
-	sshArgs := []string{
-		"ssh", "-o", "StrictHostKeyChecking=no", "-o", "UserKnownHostsFile=/dev/null",
-		"-o", "ConnectTimeout=5",
+	if err := c.SSHOptions.Validate(); err != nil {
+		return "", "", nil, err
+	}
+
+	if err := c.Filter.Validate(); err != nil {
+		return "", "", nil, err
 	}
+
+	if err := ValidateBwLimit(c.BwLimit); err != nil {
+		return "", "", nil, err
+	}
+
+	if err := c.LargeFile.Validate(); err != nil {
+		return "", "", nil, err
+	}
+
+	if err := c.Ownership.Validate(); err != nil {
+		return "", "", nil, err
+	}
+
+	if c.NoChown && !c.Ownership.Empty() {
+		return "", "", nil, errors.New("cannot change the owners of the files without preserving them")
+	}
+
+	for _, arg := range c.ExtraArgs {
+		if !strings.HasPrefix(arg, "-") {
+			return "", "", nil, fmt.Errorf("extra rsync argument is not an option: %q", arg)
+		}
+	}
+
+	sshArgs := append([]string{"ssh"}, c.SSHOptions.Args()...)
 	if c.Port != 0 {
 		sshArgs = append(sshArgs, "-p", strconv.Itoa(c.Port))
 	}
 
-	sshArgsStr := fmt.Sprintf("\"%s\"", strings.Join(sshArgs, " "))
+	if c.SSHIdentityFile != "" {
+		sshArgs = append(sshArgs, "-i", c.SSHIdentityFile)
+	}
+
+	if c.SSHProxyCommand != "" {
+		// rsync splits the remote shell command honoring quotes, but does not support escaping them
+		if strings.ContainsAny(c.SSHProxyCommand, `'"`) {
+			return "", "", nil, errors.New("ssh proxy command cannot contain quotes")
+		}
+
+		if len(c.SSHOptions.ProxyJump) > 0 {
+			return "", "", nil, errors.New("ssh proxy jump cannot be used together with a proxy command")
+		}
+
+		sshArgs = append(sshArgs, "-o", "'ProxyCommand="+c.SSHProxyCommand+"'")
+	}
+
+	// rsync splits the remote shell command into its arguments itself
+	sshCmd := strings.Join(sshArgs, " ")
 
 	rsyncArgs := []string{
-		"-av", "--info=progress2,misc0,flist0",
-		"--no-inc-recursive", "-e", sshArgsStr,
+		"-av", "--info=progress2,misc0,flist0", "--stats",
+		"--no-inc-recursive", "-e", sshCmd,
 	}
 
 	if c.Compress {
@@ -56,28 +187,53 @@ func (c *Cmd) Build() (string, error) {
 		rsyncArgs = append(rsyncArgs, "--no-o", "--no-g")
 	}
 
+	rsyncArgs = append(rsyncArgs, c.Preserve.Args()...)
+	rsyncArgs = append(rsyncArgs, c.LargeFile.Args()...)
+	rsyncArgs = append(rsyncArgs, c.Ownership.Args()...)
+
 	if c.Delete {
 		rsyncArgs = append(rsyncArgs, "--delete")
 	}
 
-	rsyncArgsStr := strings.Join(rsyncArgs, " ")
+	if c.BwLimit != "" {
+		rsyncArgs = append(rsyncArgs, "--bwlimit="+c.BwLimit)
+	}
+
+	if c.ItemizeChanges {
+		rsyncArgs = append(rsyncArgs, "--out-format="+progress.ChangeOutFormat)
+	}
 
-	src := c.buildSrc()
-	dest := c.buildDest()
+	rsyncArgs = append(rsyncArgs, c.ExtraArgs...)
 
-	return fmt.Sprintf("%s %s %s %s", cmd, rsyncArgsStr, src, dest), nil
+	return cmd, sshCmd, rsyncArgs, nil
+}
+
+// invocation returns the given rsync command, exiting successfully if only some source files vanished
+// during the transfer and AllowVanished is set.
+func (c *Cmd) invocation(invocation string) string {
+	if !c.AllowVanished {
+		return invocation
+	}
+
+	return fmt.Sprintf(`{ %s || { code=$?; if [ "$code" -eq %d ]; then `+
+		`echo "ignoring the source files which vanished during the transfer"; else (exit "$code"); fi; }; }`,
+		invocation, vanishedExitCode)
+}
+
+// ValidateBwLimit returns an error if the bandwidth limit is not a number with an optional K, M or G suffix.
+func ValidateBwLimit(bwLimit string) error {
+	if bwLimit != "" && !bwLimitRegex.MatchString(bwLimit) {
+		return fmt.Errorf("invalid bandwidth limit, expected a rate like 500K or 10M: %s", bwLimit)
+	}
+
+	return nil
 }
 
 func (c *Cmd) buildSrc() string {
 	var src strings.Builder
 
 	if c.SrcUseSSH {
-		sshDestUser := "root"
-		if c.SrcSSHUser != "" {
-			sshDestUser = c.SrcSSHUser
-		}
-
-		src.WriteString(fmt.Sprintf("%s@%s:", sshDestUser, c.SrcSSHHost))
+		src.WriteString(sshRemote(c.SrcSSHUser, c.SrcSSHHost) + ":")
 	}
 
 	src.WriteString(c.SrcPath)
@@ -89,15 +245,19 @@ func (c *Cmd) buildDest() string {
 	var dest strings.Builder
 
 	if c.DestUseSSH {
-		sshDestUser := "root"
-		if c.DestSSHUser != "" {
-			sshDestUser = c.DestSSHUser
-		}
-
-		dest.WriteString(fmt.Sprintf("%s@%s:", sshDestUser, c.DestSSHHost))
+		dest.WriteString(sshRemote(c.DestSSHUser, c.DestSSHHost) + ":")
 	}
 
 	dest.WriteString(c.DestPath)
 
 	return dest.String()
 }
+
+// sshRemote returns the user and the host of the remote, logging in as root if the user is empty.
+func sshRemote(user, host string) string {
+	if user == "" {
+		user = "root"
+	}
+
+	return user + "@" + host
+}
@@ -0,0 +1,188 @@
+package rsync
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildAllowVanished(t *testing.T) {
+	t.Parallel()
+
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+
+	run := func(exitCode string, allowVanished bool) int {
+		fakeRsync := filepath.Join(t.TempDir(), "rsync")
+		require.NoError(t, os.WriteFile(fakeRsync, []byte("#!/bin/sh\nexit "+exitCode+"\n"), 0o700))
+
+		cmd := Cmd{Command: fakeRsync, SrcPath: "/source/", DestPath: "/dest/", AllowVanished: allowVanished}
+
+		built, err := cmd.Build()
+		require.NoError(t, err)
+
+		// the command is followed by the retry loop of the job, which reads its exit code
+		var exitErr *exec.ExitError
+		if err = exec.Command("sh", "-c", built+`; exit $?`).Run(); errors.As(err, &exitErr) {
+			return exitErr.ExitCode()
+		}
+
+		require.NoError(t, err)
+
+		return 0
+	}
+
+	assert.Equal(t, 24, run("24", false))
+	assert.Equal(t, 0, run("24", true))
+	assert.Equal(t, 23, run("23", true))
+	assert.Equal(t, 0, run("0", true))
+}
+
+func TestBuildExtraArgs(t *testing.T) {
+	t.Parallel()
+
+	cmd := Cmd{SrcPath: "/source/", DestPath: "/dest/", ExtraArgs: []string{"--partial", "--inplace"}}
+
+	built, err := cmd.Build()
+	require.NoError(t, err)
+	assert.Contains(t, built, "--partial --inplace /source/ /dest/")
+
+	cmd.ExtraArgs = []string{"--partial", "/other/"}
+
+	_, err = cmd.Build()
+	require.ErrorContains(t, err, `extra rsync argument is not an option: "/other/"`)
+
+	// the parallel streams and the wrapper of the vanished files are shell scripts around the rsync argv
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash is not available")
+	}
+
+	fakeRsync := filepath.Join(t.TempDir(), "rsync")
+	require.NoError(t, os.WriteFile(fakeRsync, []byte(testArgsRsyncScript), 0o700))
+
+	cmd = Cmd{
+		Command: fakeRsync, SrcPath: "/source/", DestPath: "/dest/", Parallel: 2, AllowVanished: true,
+		ExtraArgs: []string{"--partial", "--exclude=*.tmp"},
+	}
+
+	built, err = cmd.Build()
+	require.NoError(t, err)
+
+	argsDir := t.TempDir()
+	command := exec.Command(bash, "-c", built)
+	command.Dir = argsDir
+	command.Env = append(os.Environ(), "ARGS_DIR="+argsDir)
+
+	output, err := command.CombinedOutput()
+	require.NoError(t, err, string(output))
+
+	entries, err := os.ReadDir(argsDir)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	for _, entry := range entries {
+		data, err := os.ReadFile(filepath.Join(argsDir, entry.Name()))
+		require.NoError(t, err)
+
+		args := strings.Split(strings.TrimSuffix(string(data), "\x00"), "\x00")
+		assert.Contains(t, args, "--partial")
+		assert.Contains(t, args, "--exclude=*.tmp")
+		assert.Equal(t, []string{"/source/", "/dest/"}, args[len(args)-2:])
+	}
+}
+
+// testArgsRsyncScript records its arguments separated by NUL characters into a new file in $ARGS_DIR,
+// listing no entries with --list-only.
+const testArgsRsyncScript = `#!/bin/sh
+case "$*" in
+*--list-only*) ;;
+*) printf '%s\0' "$@" > "$ARGS_DIR/$$" ;;
+esac
+`
+
+func FuzzBuildPaths(f *testing.F) {
+	for _, path := range []string{
+		"/source/", "/data/my dir/", "/data/it's", `/data/"quoted"`, "/data/$(touch pwned)", "/data/`id`",
+		"/data/a\nb", "/data/back\\slash", "/data/*", "~", "-rf", "/data/ ; rm -rf / #", "/data/\t$HOME'\"",
+	} {
+		f.Add(path, path+"/dest")
+	}
+
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		f.Skip("sh is not available")
+	}
+
+	// the transfer pods run busybox sh, which supports pipefail unlike some sh implementations
+	bash, _ := exec.LookPath("bash")
+
+	fakeRsync := filepath.Join(f.TempDir(), "rsync")
+	require.NoError(f, os.WriteFile(fakeRsync, []byte(testArgsRsyncScript), 0o700))
+
+	f.Fuzz(func(t *testing.T, srcPath, destPath string) {
+		// the arguments of a process cannot contain NUL characters
+		if strings.ContainsRune(srcPath, 0) || strings.ContainsRune(destPath, 0) {
+			t.Skip()
+		}
+
+		cmd := Cmd{
+			Command: fakeRsync, SrcUseSSH: true, SrcSSHHost: "sshd", SrcPath: srcPath, DestPath: destPath,
+			Filter: Filter{Excludes: []string{"it's $(*).log"}}, AllowVanished: true,
+		}
+
+		args, err := cmd.Args()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"root@sshd:" + srcPath, destPath}, args[len(args)-2:])
+
+		run := func(shell string, parallel int) [][]string {
+			cmd.Parallel = parallel
+
+			built, err := cmd.Build()
+			require.NoError(t, err)
+
+			argsDir := t.TempDir()
+			command := exec.Command(shell, "-c", built)
+			command.Dir = argsDir
+			command.Env = append(os.Environ(), "ARGS_DIR="+argsDir)
+
+			output, err := command.CombinedOutput()
+			require.NoError(t, err, string(output))
+
+			entries, err := os.ReadDir(argsDir)
+			require.NoError(t, err)
+
+			invocations := make([][]string, 0, len(entries))
+
+			for _, entry := range entries {
+				data, err := os.ReadFile(filepath.Join(argsDir, entry.Name()))
+				require.NoError(t, err)
+
+				invocations = append(invocations, strings.Split(strings.TrimSuffix(string(data), "\x00"), "\x00"))
+			}
+
+			return invocations
+		}
+
+		assert.Equal(t, [][]string{args[1:]}, run(sh, 1))
+
+		if bash == "" {
+			return
+		}
+
+		invocations := run(bash, 2)
+		require.Len(t, invocations, 2)
+
+		for _, invocation := range invocations {
+			assert.Equal(t, args[len(args)-2:], invocation[len(invocation)-2:])
+			assert.Contains(t, invocation, "--exclude=it's $(*).log")
+		}
+	})
+}
@@ -0,0 +1,58 @@
+package rsync
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Filter are the rules which select the files to transfer. The first matching rule wins,
+// in the order of the includes, the excludes and the rules in the rules file.
+type Filter struct {
+	// Includes are the patterns of the files to transfer even if they match an exclude, see INCLUDE/EXCLUDE PATTERN
+	// RULES in rsync(1).
+	Includes []string
+	// Excludes are the patterns of the files not to transfer.
+	Excludes []string
+	// RulesFile is the path of a file with filter rules in the syntax of the --filter flag of rsync, one per line.
+	RulesFile string
+}
+
+// Empty returns true if the filter has no rules, i.e. all files are transferred.
+func (f *Filter) Empty() bool {
+	return len(f.Includes) == 0 && len(f.Excludes) == 0 && f.RulesFile == ""
+}
+
+// Validate returns an error if any of the patterns is invalid.
+func (f *Filter) Validate() error {
+	for _, pattern := range append(append([]string{}, f.Includes...), f.Excludes...) {
+		if strings.TrimSpace(pattern) == "" {
+			return errors.New("filter pattern cannot be empty")
+		}
+
+		if strings.ContainsAny(pattern, "\n\r") {
+			return fmt.Errorf("filter pattern cannot contain newlines: %q", pattern)
+		}
+	}
+
+	return nil
+}
+
+// Args returns the rsync arguments for the filter.
+func (f *Filter) Args() []string {
+	args := make([]string, 0, len(f.Includes)+len(f.Excludes)+1)
+
+	for _, pattern := range f.Includes {
+		args = append(args, "--include="+pattern)
+	}
+
+	for _, pattern := range f.Excludes {
+		args = append(args, "--exclude="+pattern)
+	}
+
+	if f.RulesFile != "" {
+		args = append(args, "--filter=merge "+f.RulesFile)
+	}
+
+	return args
+}
@@ -0,0 +1,121 @@
+package rsync
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	LargeFileModePartial      = "partial"
+	LargeFileModeInplace      = "inplace"
+	LargeFileModeAppendVerify = "append-verify"
+	LargeFileModeChecksum     = "checksum"
+
+	// PartialDir is where the interrupted files are kept in the destination in the partial mode,
+	// relative to the directory of each file.
+	PartialDir = ".pv-migrate-partial"
+)
+
+var LargeFileModes = []string{
+	LargeFileModePartial, LargeFileModeInplace, LargeFileModeAppendVerify, LargeFileModeChecksum,
+}
+
+// LargeFile are the options of transferring large files, to resume them instead of resending them from the start
+// when the transfer is retried.
+//
+// At most one of Partial, Inplace and AppendVerify can be set.
+type LargeFile struct {
+	// Partial keeps the interrupted files in PartialDir, to be completed by the next transfer (--partial-dir).
+	Partial bool
+	// Inplace writes the files directly into the destination instead of a temporary file (--inplace).
+	Inplace bool
+	// AppendVerify appends the missing data to the shorter files on the destination,
+	// verifying the whole file afterward (--append-verify).
+	AppendVerify bool
+	// Checksum compares the files by their checksums instead of their sizes and modification times (--checksum).
+	Checksum bool
+}
+
+// ParseLargeFile returns the large file options of the given modes.
+func ParseLargeFile(modes []string) (LargeFile, error) {
+	var largeFile LargeFile
+
+	for _, mode := range modes {
+		switch mode {
+		case LargeFileModePartial:
+			largeFile.Partial = true
+		case LargeFileModeInplace:
+			largeFile.Inplace = true
+		case LargeFileModeAppendVerify:
+			largeFile.AppendVerify = true
+		case LargeFileModeChecksum:
+			largeFile.Checksum = true
+		default:
+			return LargeFile{}, fmt.Errorf("invalid large file mode %q, valid values are %s",
+				mode, strings.Join(LargeFileModes, ","))
+		}
+	}
+
+	if err := largeFile.Validate(); err != nil {
+		return LargeFile{}, err
+	}
+
+	return largeFile, nil
+}
+
+// Validate returns an error if more than one way of resuming the files is set.
+func (l *LargeFile) Validate() error {
+	resumeModes := 0
+
+	for _, set := range []bool{l.Partial, l.Inplace, l.AppendVerify} {
+		if set {
+			resumeModes++
+		}
+	}
+
+	if resumeModes > 1 {
+		return errors.New("only one of the large file modes " + LargeFileModePartial + ", " +
+			LargeFileModeInplace + " and " + LargeFileModeAppendVerify + " can be used")
+	}
+
+	return nil
+}
+
+// Empty returns true if none of the options is set.
+func (l *LargeFile) Empty() bool {
+	return *l == LargeFile{}
+}
+
+// Modes returns the names of the set options, as accepted by ParseLargeFile.
+func (l *LargeFile) Modes() []string {
+	var modes []string
+
+	for i, set := range []bool{l.Partial, l.Inplace, l.AppendVerify, l.Checksum} {
+		if set {
+			modes = append(modes, LargeFileModes[i])
+		}
+	}
+
+	return modes
+}
+
+// Args returns the rsync arguments for the options.
+func (l *LargeFile) Args() []string {
+	var args []string
+
+	switch {
+	case l.Partial:
+		args = append(args, "--partial-dir="+PartialDir)
+	case l.Inplace:
+		args = append(args, "--inplace")
+	case l.AppendVerify:
+		args = append(args, "--append-verify")
+	}
+
+	if l.Checksum {
+		args = append(args, "--checksum")
+	}
+
+	return args
+}
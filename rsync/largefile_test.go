@@ -0,0 +1,37 @@
+package rsync
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLargeFile(t *testing.T) {
+	t.Parallel()
+
+	largeFile, err := ParseLargeFile(nil)
+	require.NoError(t, err)
+	assert.True(t, largeFile.Empty())
+	assert.Empty(t, largeFile.Args())
+
+	largeFile, err = ParseLargeFile([]string{LargeFileModeChecksum, LargeFileModePartial})
+	require.NoError(t, err)
+	assert.Equal(t, LargeFile{Partial: true, Checksum: true}, largeFile)
+	assert.Equal(t, []string{"--partial-dir=" + PartialDir, "--checksum"}, largeFile.Args())
+	assert.Equal(t, []string{LargeFileModePartial, LargeFileModeChecksum}, largeFile.Modes())
+
+	largeFile, err = ParseLargeFile([]string{LargeFileModeInplace})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"--inplace"}, largeFile.Args())
+
+	largeFile, err = ParseLargeFile([]string{LargeFileModeAppendVerify})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"--append-verify"}, largeFile.Args())
+
+	_, err = ParseLargeFile([]string{LargeFileModePartial, LargeFileModeInplace})
+	require.Error(t, err)
+
+	_, err = ParseLargeFile([]string{"sparse"})
+	require.Error(t, err)
+}
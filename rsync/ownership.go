@@ -0,0 +1,76 @@
+package rsync
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var (
+	// chownRegex matches the USER:GROUP of --chown, either of which can be empty, by name or ID.
+	chownRegex = regexp.MustCompile(`^[A-Za-z0-9_.-]*(:[A-Za-z0-9_.-]*)?$`)
+
+	// ownerMapRuleRegex matches a FROM:TO rule of --usermap and --groupmap. FROM can be a name or an ID,
+	// a range of IDs like 0-99, or a wildcard pattern like *.
+	ownerMapRuleRegex = regexp.MustCompile(`^[A-Za-z0-9_.*?\[\]-]+:[A-Za-z0-9_.-]+$`)
+)
+
+// Ownership changes the owners of the files on the destination, instead of preserving the ones of the source.
+//
+// Chown cannot be combined with UserMap and GroupMap, as rsync implements it with them.
+type Ownership struct {
+	// Chown is the USER:GROUP to own all the files, by name or ID. Either can be empty to keep it, e.g. :1000 (--chown).
+	Chown string
+	// UserMap are the FROM:TO rules mapping the users of the source to the ones of the destination,
+	// e.g. 1000:2000 or *:2000. The first matching rule wins (--usermap).
+	UserMap []string
+	// GroupMap are the FROM:TO rules mapping the groups of the source to the ones of the destination (--groupmap).
+	GroupMap []string
+}
+
+// Validate returns an error if the chown or one of the rules is malformed, or they are combined.
+func (o *Ownership) Validate() error {
+	if o.Chown != "" {
+		if !chownRegex.MatchString(o.Chown) || strings.Trim(o.Chown, ":") == "" {
+			return fmt.Errorf("invalid chown, expected USER:GROUP: %s", o.Chown)
+		}
+
+		if len(o.UserMap) > 0 || len(o.GroupMap) > 0 {
+			return errors.New("chown cannot be combined with the user and group maps")
+		}
+	}
+
+	for _, rule := range slices.Concat(o.UserMap, o.GroupMap) {
+		if !ownerMapRuleRegex.MatchString(rule) {
+			return fmt.Errorf("invalid owner map rule, expected FROM:TO: %s", rule)
+		}
+	}
+
+	return nil
+}
+
+// Empty returns true if the owners of the source are preserved.
+func (o *Ownership) Empty() bool {
+	return o.Chown == "" && len(o.UserMap) == 0 && len(o.GroupMap) == 0
+}
+
+// Args returns the rsync arguments for the ownership.
+func (o *Ownership) Args() []string {
+	var args []string
+
+	if o.Chown != "" {
+		args = append(args, "--chown="+o.Chown)
+	}
+
+	if len(o.UserMap) > 0 {
+		args = append(args, "--usermap="+strings.Join(o.UserMap, ","))
+	}
+
+	if len(o.GroupMap) > 0 {
+		args = append(args, "--groupmap="+strings.Join(o.GroupMap, ","))
+	}
+
+	return args
+}
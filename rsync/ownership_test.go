@@ -0,0 +1,44 @@
+package rsync
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOwnership(t *testing.T) {
+	t.Parallel()
+
+	var ownership Ownership
+
+	require.NoError(t, ownership.Validate())
+	assert.True(t, ownership.Empty())
+	assert.Empty(t, ownership.Args())
+
+	ownership = Ownership{Chown: "1000:2000"}
+	require.NoError(t, ownership.Validate())
+	assert.Equal(t, []string{"--chown=1000:2000"}, ownership.Args())
+
+	ownership = Ownership{UserMap: []string{"0-99:1000", "app:www-data"}, GroupMap: []string{"*:1000"}}
+	require.NoError(t, ownership.Validate())
+	assert.False(t, ownership.Empty())
+	assert.Equal(t, []string{"--usermap=0-99:1000,app:www-data", "--groupmap=*:1000"}, ownership.Args())
+
+	for _, chown := range []string{":1000", "1000", "app:", "app:www-data"} {
+		ownership = Ownership{Chown: chown}
+		assert.NoError(t, ownership.Validate(), chown)
+	}
+
+	for _, invalid := range []Ownership{
+		{Chown: ":"},
+		{Chown: "1000:2000:3000"},
+		{Chown: "$(id)"},
+		{Chown: "1000", UserMap: []string{"0:1000"}},
+		{UserMap: []string{"1000"}},
+		{UserMap: []string{"1000:2000,0:0"}},
+		{GroupMap: []string{"*:"}},
+	} {
+		assert.Error(t, invalid.Validate(), invalid)
+	}
+}
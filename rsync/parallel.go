@@ -0,0 +1,53 @@
+package rsync
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/utkuozdemir/pv-migrate/rsync/progress"
+)
+
+// splitStreamsAwk splits the top-level entries in the output of rsync --list-only into the filter files
+// of the streams, round-robin.
+//
+// The stream 0 excludes the entries of the other streams, so that it also copies the entries which appear
+// after the listing, and deletes the extraneous top-level entries on the destination. The other streams
+// include only their entries.
+const splitStreamsAwk = `{ link = substr($0, 1, 1) == "l"; name = $0; ` +
+	`sub(/^[^ ]+ +[^ ]+ +[^ ]+ +[^ ]+ /, "", name); if (link) sub(/ -> .*$/, "", name); ` +
+	`if (name == ".") next; if (name ~ /[*?[]/) gsub(/[*?[\\]/, "\\\\&", name); ` +
+	`stream = count++ % n; if (stream > 0) { print "+ /" name > (dir "/" stream); print "- /" name > (dir "/0") } } ` +
+	`END { printf "" > (dir "/0"); for (i = 1; i < n; i++) print "- /*" > (dir "/" i) }`
+
+// buildParallel builds the command which runs the given number of rsync processes concurrently,
+// each copying a part of the top-level entries of the source.
+//
+// The output lines of each process are prefixed with progress.StreamPrefixFormat, and the command exits
+// with the exit code of a failed process, if any.
+func (c *Cmd) buildParallel(cmd, sshCmd string, rsyncArgs []string, src, dest string) string {
+	filterArgs := c.Filter.Args()
+	listArgs := slices.Concat([]string{cmd, "--list-only", "-d", "-e", sshCmd}, filterArgs, []string{src})
+	// the filter file of the stream is only known when the command runs, so it is expanded by the shell.
+	// It comes before the filter rules of the transfer, as the first matching rule wins, so that the top-level
+	// entries which are included by them are still copied by a single stream only
+	streamCmd := shellJoin(slices.Concat([]string{cmd}, rsyncArgs)) +
+		` --filter="merge $streams/$i" ` + shellJoin(slices.Concat(filterArgs, []string{src, dest}))
+	streams := c.Parallel
+	prefix := fmt.Sprintf(progress.StreamPrefixFormat, "$i")
+
+	var script strings.Builder
+
+	script.WriteString(`(set -o pipefail; streams=$(mktemp -d) || exit 1; trap 'rm -rf "$streams"' EXIT; `)
+	script.WriteString(fmt.Sprintf(`%s | awk -v n=%d -v dir="$streams" '%s' || exit $?; `,
+		shellJoin(listArgs), streams, splitStreamsAwk))
+	script.WriteString(`rc=0; pids=""; i=0; `)
+	script.WriteString(fmt.Sprintf(`while [ "$i" -lt %d ]; do (%s 2>&1 | `,
+		streams, c.invocation(streamCmd)))
+	script.WriteString(`while IFS= read -r line || [ -n "$line" ]; do `)
+	script.WriteString(fmt.Sprintf(`printf '%%s%%s\n' "%s" "$line"; done) & `, prefix))
+	script.WriteString(`pids="$pids $!"; i=$((i+1)); done; `)
+	script.WriteString(`for pid in $pids; do wait "$pid" || rc=$?; done; exit $rc)`)
+
+	return script.String()
+}
@@ -0,0 +1,111 @@
+package rsync
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testFakeRsyncScript lists a fixed set of top-level entries with --list-only, and otherwise prints
+// the rules of the merged filter file of the stream, failing for the stream which includes "fail".
+const testFakeRsyncScript = `#!/bin/sh
+case "$*" in
+*--list-only*)
+  cat <<'EOF'
+drwxr-xr-x          4,096 2024/01/01 12:00:00 .
+drwxr-xr-x          4,096 2024/01/01 12:00:00 data
+-rw-r--r--    123,456,789 2024/01/01 12:00:00 my file.db
+lrwxrwxrwx              4 2024/01/01 12:00:00 link -> data
+-rw-r--r--              1 2024/01/01 12:00:00 weird*name
+EOF
+  ;;
+*)
+  for arg in "$@"; do
+    case "$arg" in
+    --filter=merge*) rules="${arg#--filter=merge }" ;;
+    esac
+  done
+  tr '\n' ';' < "$rules"
+  echo
+  echo "total size is 100  speedup is 1.00"
+  ! grep -q fail "$rules"
+  ;;
+esac
+`
+
+func TestBuildParallel(t *testing.T) {
+	t.Parallel()
+
+	cmd := Cmd{SrcPath: "/source/", DestPath: "/dest/", Parallel: 3, Delete: true}
+
+	built, err := cmd.Build()
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(built, "(set -o pipefail; streams=$(mktemp -d)"), built)
+	assert.Contains(t, built, "rsync --list-only -d -e ")
+	assert.Contains(t, built, `--delete --filter="merge $streams/$i" /source/ /dest/ 2>&1 | `)
+
+	cmd.Filter = Filter{Includes: []string{"/data"}, Excludes: []string{"/*"}}
+
+	built, err = cmd.Build()
+	require.NoError(t, err)
+	assert.Contains(t, built, `--delete --filter="merge $streams/$i" --include=/data '--exclude=/*' /source/ /dest/`)
+}
+
+func TestBuildParallelRun(t *testing.T) {
+	t.Parallel()
+
+	// the transfer pods run busybox sh, which supports pipefail unlike some sh implementations
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash is not available")
+	}
+
+	fakeRsync := filepath.Join(t.TempDir(), "rsync")
+	require.NoError(t, os.WriteFile(fakeRsync, []byte(testFakeRsyncScript), 0o700))
+
+	cmd := Cmd{Command: fakeRsync, SrcPath: "/source/", DestPath: "/dest/", Parallel: 2}
+
+	built, err := cmd.Build()
+	require.NoError(t, err)
+
+	output, err := exec.Command(bash, "-c", built).CombinedOutput()
+	require.NoError(t, err, string(output))
+
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	sort.Strings(lines)
+
+	assert.Equal(t, []string{
+		`[stream 0] - /my file.db;- /weird\*name;`,
+		"[stream 0] total size is 100  speedup is 1.00",
+		`[stream 1] + /my file.db;+ /weird\*name;- /*;`,
+		"[stream 1] total size is 100  speedup is 1.00",
+	}, lines)
+}
+
+func TestBuildParallelRunFailure(t *testing.T) {
+	t.Parallel()
+
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash is not available")
+	}
+
+	fakeRsync := filepath.Join(t.TempDir(), "rsync")
+	script := strings.Replace(testFakeRsyncScript, "link -> data", "fail", 1)
+	require.NoError(t, os.WriteFile(fakeRsync, []byte(script), 0o700))
+
+	cmd := Cmd{Command: fakeRsync, SrcPath: "/source/", DestPath: "/dest/", Parallel: 3}
+
+	built, err := cmd.Build()
+	require.NoError(t, err)
+
+	var exitErr *exec.ExitError
+	require.ErrorAs(t, exec.Command(bash, "-c", built).Run(), &exitErr)
+	assert.Equal(t, 1, exitErr.ExitCode())
+}
@@ -0,0 +1,162 @@
+package rsync
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	PreserveHardlinks  = "hardlinks"
+	PreserveACLs       = "acls"
+	PreserveXattrs     = "xattrs"
+	PreserveSparse     = "sparse"
+	PreserveNumericIDs = "numeric-ids"
+
+	// FidelityDefault preserves what the -a flag of rsync preserves.
+	FidelityDefault = "default"
+	// FidelityFull preserves everything rsync can preserve.
+	FidelityFull = "full"
+
+	// unsupportedExitCode is the exit code of rsync for "requested action not supported".
+	unsupportedExitCode = 4
+)
+
+var (
+	PreserveOptions = []string{
+		PreserveHardlinks, PreserveACLs, PreserveXattrs, PreserveSparse, PreserveNumericIDs,
+	}
+
+	Fidelities = []string{FidelityDefault, FidelityFull}
+)
+
+// Preserve are the attributes of the files which are preserved in addition to the ones the -a flag of rsync preserves.
+type Preserve struct {
+	// Hardlinks preserves the hard links between the files (-H).
+	Hardlinks bool
+	// ACLs preserves the POSIX ACLs of the files (-A).
+	ACLs bool
+	// Xattrs preserves the extended attributes of the files (-X).
+	Xattrs bool
+	// Sparse keeps the sparse files sparse on the destination (-S).
+	Sparse bool
+	// NumericIDs preserves the owners by their IDs instead of mapping them by their names (--numeric-ids).
+	NumericIDs bool
+}
+
+// ParsePreserve returns the preserve options of the fidelity preset, with the given options added.
+func ParsePreserve(fidelity string, options []string) (Preserve, error) {
+	var preserve Preserve
+
+	switch fidelity {
+	case "", FidelityDefault:
+	case FidelityFull:
+		options = PreserveOptions
+	default:
+		return Preserve{}, fmt.Errorf("invalid fidelity %q, valid values are %s",
+			fidelity, strings.Join(Fidelities, ","))
+	}
+
+	for _, option := range options {
+		switch option {
+		case PreserveHardlinks:
+			preserve.Hardlinks = true
+		case PreserveACLs:
+			preserve.ACLs = true
+		case PreserveXattrs:
+			preserve.Xattrs = true
+		case PreserveSparse:
+			preserve.Sparse = true
+		case PreserveNumericIDs:
+			preserve.NumericIDs = true
+		default:
+			return Preserve{}, fmt.Errorf("invalid preserve option %q, valid values are %s",
+				option, strings.Join(PreserveOptions, ","))
+		}
+	}
+
+	return preserve, nil
+}
+
+// Empty returns true if nothing is preserved in addition to what -a preserves.
+func (p *Preserve) Empty() bool {
+	return *p == Preserve{}
+}
+
+// Options returns the names of the preserved attributes, as accepted by ParsePreserve.
+func (p *Preserve) Options() []string {
+	var options []string
+
+	for i, set := range []bool{p.Hardlinks, p.ACLs, p.Xattrs, p.Sparse, p.NumericIDs} {
+		if set {
+			options = append(options, PreserveOptions[i])
+		}
+	}
+
+	return options
+}
+
+// Args returns the rsync arguments for the preserve options.
+func (p *Preserve) Args() []string {
+	var args []string
+
+	if p.Hardlinks {
+		args = append(args, "-H")
+	}
+
+	if p.ACLs {
+		args = append(args, "-A")
+	}
+
+	if p.Xattrs {
+		args = append(args, "-X")
+	}
+
+	if p.Sparse {
+		args = append(args, "-S")
+	}
+
+	if p.NumericIDs {
+		args = append(args, "--numeric-ids")
+	}
+
+	return args
+}
+
+// capabilityCheck returns the shell commands which exit with the "requested action not supported" exit code
+// of rsync if the rsync binary was built without the capabilities needed by the preserve options.
+// versionCmd is the shell command printing the version of the rsync binary, which is described by name.
+// A failure of versionCmd, e.g. of the ssh connection to the remote, exits with its own exit code.
+//
+// The capabilities are read from the output of rsync --version, where they are listed separated by commas,
+// prefixed with "no " if they are missing.
+func (p *Preserve) capabilityCheck(versionCmd, name string) string {
+	var capabilities []string
+
+	if p.Hardlinks {
+		capabilities = append(capabilities, "hardlinks")
+	}
+
+	if p.ACLs {
+		capabilities = append(capabilities, "ACLs")
+	}
+
+	if p.Xattrs {
+		capabilities = append(capabilities, "xattrs")
+	}
+
+	if len(capabilities) == 0 {
+		return ""
+	}
+
+	var check strings.Builder
+
+	check.WriteString(fmt.Sprintf(`rsync_version=$(%s) || exit $?; `, versionCmd))
+
+	for _, capability := range capabilities {
+		check.WriteString(fmt.Sprintf(`printf '%%s\n' "$rsync_version" | grep -Eq '(^|,)[[:space:]]*%s(,|$)' || `+
+			`{ echo %s >&2; exit %d; }; `,
+			capability, shellQuote(name+" does not support "+capability), unsupportedExitCode))
+	}
+
+	return check.String()
+}
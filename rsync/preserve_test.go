@@ -0,0 +1,118 @@
+package rsync
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testVersionOutput = `rsync  version 3.2.7  protocol version 31
+Copyright (C) 1996-2022 by Andrew Tridgell, Wayne Davison, and others.
+Web site: https://rsync.samba.org/
+Capabilities:
+    64-bit files, 64-bit inums, 64-bit timestamps, 64-bit long ints,
+    socketpairs, hardlinks, hardlink-specials, hardlink-symlinks, IPv6, atimes,
+    batchfiles, inplace, append, ACLs, no xattrs, optional secluded-args, iconv,
+    prealloc, stop-at, no crtimes
+`
+)
+
+func TestParsePreserve(t *testing.T) {
+	t.Parallel()
+
+	preserve, err := ParsePreserve(FidelityDefault, nil)
+	require.NoError(t, err)
+	assert.True(t, preserve.Empty())
+
+	preserve, err = ParsePreserve(FidelityDefault, []string{PreserveHardlinks, PreserveNumericIDs})
+	require.NoError(t, err)
+	assert.Equal(t, Preserve{Hardlinks: true, NumericIDs: true}, preserve)
+	assert.Equal(t, []string{"-H", "--numeric-ids"}, preserve.Args())
+	assert.Equal(t, []string{PreserveHardlinks, PreserveNumericIDs}, preserve.Options())
+
+	preserve, err = ParsePreserve(FidelityFull, []string{PreserveSparse})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"-H", "-A", "-X", "-S", "--numeric-ids"}, preserve.Args())
+
+	_, err = ParsePreserve("high", nil)
+	require.Error(t, err)
+
+	_, err = ParsePreserve(FidelityDefault, []string{"times"})
+	require.Error(t, err)
+}
+
+func TestPreserveCapabilityCheck(t *testing.T) {
+	t.Parallel()
+
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh is not available")
+	}
+
+	// the rsync of the remote, run over the fake ssh, lacks the support of ACLs, and the host "down" is unreachable
+	binDir := t.TempDir()
+	fakeRsync := filepath.Join(binDir, "rsync")
+	require.NoError(t, os.WriteFile(fakeRsync, []byte("#!/bin/sh\ncat <<'EOF'\n"+testVersionOutput+"EOF\n"), 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(binDir, "ssh"), []byte("#!/bin/sh\n"+
+		"case \"$*\" in *@down*) exit 255 ;; esac\n"+
+		"sed 's/ ACLs/ no ACLs/' <<'EOF'\n"+testVersionOutput+"EOF\n"), 0o700))
+
+	run := func(cmd Cmd) (int, string) {
+		cmd.Command, cmd.SrcPath, cmd.DestPath = fakeRsync, "/source/", "/dest/"
+
+		built, err := cmd.Build()
+		require.NoError(t, err)
+
+		rsyncCmd, sshCmd, _, err := cmd.prepare()
+		require.NoError(t, err)
+
+		// replace the transfer itself with a no-op, only the capability check is run
+		check := cmd.capabilityCheck(rsyncCmd, sshCmd)
+		assert.True(t, strings.HasPrefix(built, check), built)
+
+		command := exec.Command("sh", "-c", check+"true")
+		command.Env = append(os.Environ(), "PATH="+binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+		output, err := command.CombinedOutput()
+
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode(), string(output)
+		}
+
+		require.NoError(t, err)
+
+		return 0, string(output)
+	}
+
+	code, _ := run(Cmd{})
+	assert.Equal(t, 0, code)
+
+	code, _ = run(Cmd{Preserve: Preserve{Hardlinks: true, ACLs: true, Sparse: true, NumericIDs: true}})
+	assert.Equal(t, 0, code)
+
+	code, output := run(Cmd{Preserve: Preserve{Xattrs: true}})
+	assert.Equal(t, unsupportedExitCode, code)
+	assert.Contains(t, output, "rsync in the image does not support xattrs")
+
+	code, _ = run(Cmd{Preserve: Preserve{Hardlinks: true, Xattrs: true}})
+	assert.Equal(t, unsupportedExitCode, code)
+
+	code, _ = run(Cmd{Preserve: Preserve{Hardlinks: true}, SrcUseSSH: true, SrcSSHHost: "sshd"})
+	assert.Equal(t, 0, code)
+
+	code, output = run(Cmd{
+		Preserve: Preserve{ACLs: true}, DestUseSSH: true, DestSSHUser: "pv-migrate", DestSSHHost: "sshd",
+	})
+	assert.Equal(t, unsupportedExitCode, code)
+	assert.Contains(t, output, "rsync on pv-migrate@sshd does not support ACLs")
+
+	code, _ = run(Cmd{Preserve: Preserve{Hardlinks: true}, SrcUseSSH: true, SrcSSHHost: "down"})
+	assert.Equal(t, 255, code)
+}
@@ -0,0 +1,67 @@
+package progress
+
+import (
+	"strings"
+)
+
+const (
+	// ChangeOutFormat is the --out-format of rsync which outputs the itemized changes of the files,
+	// as parsed by ParseChange.
+	ChangeOutFormat = changePrefix + "%i %n"
+
+	changePrefix = "[change] "
+	// itemizedLength is the length of the %i field of the rsync output format, e.g. ">f+++++++++".
+	itemizedLength = 11
+
+	ChangeCreated  = "created"
+	ChangeUpdated  = "updated"
+	ChangeDeleted  = "deleted"
+	ChangeMetadata = "metadata"
+)
+
+// Change is a change of a file on the destination, as itemized by rsync.
+type Change struct {
+	// Action is one of ChangeCreated, ChangeUpdated, ChangeDeleted and ChangeMetadata.
+	Action string `json:"action"`
+	// Itemized is the itemized change string of rsync, see --itemize-changes in rsync(1).
+	Itemized string `json:"itemized"`
+	// Path is the path of the file relative to the destination, directories end with a slash.
+	// The non-printable characters are escaped as \#ooo by rsync.
+	Path string `json:"path"`
+}
+
+// ParseChange parses a line of the output of rsync with ChangeOutFormat, optionally prefixed with
+// StreamPrefixFormat, returning false if it is not a change line.
+func ParseChange(line string) (Change, bool) {
+	if matches := streamLineRegex.FindStringSubmatch(line); matches != nil {
+		line = matches[2]
+	}
+
+	// the progress is updated in place with carriage returns, so the change may follow an update on the same line
+	for _, segment := range strings.Split(line, "\r") {
+		rest, ok := strings.CutPrefix(segment, changePrefix)
+		if !ok || len(rest) < itemizedLength+2 || rest[itemizedLength] != ' ' {
+			continue
+		}
+
+		itemized := strings.TrimSpace(rest[:itemizedLength])
+
+		return Change{Action: changeAction(itemized), Itemized: itemized, Path: rest[itemizedLength+1:]}, true
+	}
+
+	return Change{}, false
+}
+
+// changeAction returns the action of the itemized change string.
+func changeAction(itemized string) string {
+	switch {
+	case strings.HasPrefix(itemized, "*deleting"):
+		return ChangeDeleted
+	case strings.Contains(itemized, "+++++++"):
+		return ChangeCreated
+	case strings.HasPrefix(itemized, "."):
+		return ChangeMetadata
+	default:
+		return ChangeUpdated
+	}
+}
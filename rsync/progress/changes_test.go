@@ -0,0 +1,43 @@
+package progress_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/utkuozdemir/pv-migrate/rsync/progress"
+)
+
+func TestParseChange(t *testing.T) {
+	t.Parallel()
+
+	for line, expected := range map[string]progress.Change{
+		"[change] >f+++++++++ data/my file.db": {
+			Action: progress.ChangeCreated, Itemized: ">f+++++++++", Path: "data/my file.db",
+		},
+		"[change] cd+++++++++ data/":    {Action: progress.ChangeCreated, Itemized: "cd+++++++++", Path: "data/"},
+		"[change] >f.st...... data/log": {Action: progress.ChangeUpdated, Itemized: ">f.st......", Path: "data/log"},
+		"[change] .d..t...... data/":    {Action: progress.ChangeMetadata, Itemized: ".d..t......", Path: "data/"},
+		"[change] *deleting   old/file": {Action: progress.ChangeDeleted, Itemized: "*deleting", Path: "old/file"},
+		"[stream 1] [change] >f+++++++++ a": {
+			Action: progress.ChangeCreated, Itemized: ">f+++++++++", Path: "a",
+		},
+		"      1,000  10%  1.00MB/s    0:00:09\r[change] >f+++++++++ b": {
+			Action: progress.ChangeCreated, Itemized: ">f+++++++++", Path: "b",
+		},
+	} {
+		change, ok := progress.ParseChange(line)
+		assert.True(t, ok, line)
+		assert.Equal(t, expected, change, line)
+	}
+
+	for _, line := range []string{
+		"      1,000  10%  1.00MB/s    0:00:09",
+		"[change] >f+++++++++",
+		"data/[change] >f+++++++++ x",
+		"total size is 10,000  speedup is 1.00",
+	} {
+		_, ok := progress.ParseChange(line)
+		assert.False(t, ok, line)
+	}
+}
@@ -0,0 +1,71 @@
+package progress
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+)
+
+// StreamPrefixFormat is the format of the prefix of the output lines of each stream of a parallel transfer,
+// with the index of the stream as its argument.
+const StreamPrefixFormat = "[stream %s] "
+
+var streamLineRegex = regexp.MustCompile(`^\[stream ([0-9]+)] (.*)$`)
+
+// Combined combines the progress of the streams of a parallel transfer into the progress of the whole transfer.
+//
+// It is not safe for concurrent use.
+type Combined struct {
+	streams   int
+	parseLine ParseLineFunc
+	progress  map[int]Progress
+}
+
+// NewCombined creates a Combined for the given number of streams, parsing their lines with the given function.
+func NewCombined(streams int, parseLine ParseLineFunc) *Combined {
+	return &Combined{
+		streams:   streams,
+		parseLine: parseLine,
+		progress:  make(map[int]Progress, streams),
+	}
+}
+
+// ParseLine parses a line prefixed with StreamPrefixFormat, and returns the combined progress of all streams.
+//
+// The combined progress is complete only when all streams are complete.
+func (c *Combined) ParseLine(line string) (Progress, error) {
+	matches := streamLineRegex.FindStringSubmatch(line)
+	if matches == nil {
+		return Progress{}, errors.New("no stream prefix")
+	}
+
+	stream, err := strconv.Atoi(matches[1])
+	if err != nil || stream >= c.streams {
+		return Progress{}, errors.New("invalid stream: " + matches[1])
+	}
+
+	streamProgress, err := c.parseLine(matches[2])
+	if err != nil {
+		return Progress{}, err
+	}
+
+	c.progress[stream] = streamProgress
+
+	combined := Progress{Line: line}
+	complete := len(c.progress) == c.streams
+
+	for _, p := range c.progress {
+		combined.Transferred += p.Transferred
+		combined.Total += p.Total
+		complete = complete && p.Percentage >= percentHundred
+	}
+
+	switch {
+	case complete:
+		combined.Percentage = percentHundred
+	case combined.Total > 0:
+		combined.Percentage = min(int(combined.Transferred*percentHundred/combined.Total), percentHundred-1)
+	}
+
+	return combined, nil
+}
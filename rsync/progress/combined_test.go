@@ -0,0 +1,50 @@
+package progress_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/utkuozdemir/pv-migrate/rsync/progress"
+)
+
+func TestCombinedParseLine(t *testing.T) {
+	t.Parallel()
+
+	combined := progress.NewCombined(2, progress.ParseLine)
+
+	_, err := combined.ParseLine("      1,000  10%  1.00MB/s    0:00:09")
+	require.Error(t, err)
+
+	_, err = combined.ParseLine("[stream 2]       1,000  10%  1.00MB/s    0:00:09")
+	require.Error(t, err)
+
+	p, err := combined.ParseLine("[stream 0]       1,000  10%  1.00MB/s    0:00:09")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1000), p.Transferred)
+	assert.Equal(t, int64(10000), p.Total)
+	assert.Equal(t, 10, p.Percentage)
+
+	p, err = combined.ParseLine("[stream 1] total size is 10,000  speedup is 1.00")
+	require.NoError(t, err)
+	assert.Equal(t, int64(11000), p.Transferred)
+	assert.Equal(t, int64(20000), p.Total)
+	assert.Equal(t, 55, p.Percentage)
+
+	p, err = combined.ParseLine("[stream 0] total size is 10,000  speedup is 1.00")
+	require.NoError(t, err)
+	assert.Equal(t, int64(20000), p.Transferred)
+	assert.Equal(t, 100, p.Percentage)
+}
+
+func TestCombinedParseLineIncompleteStreams(t *testing.T) {
+	t.Parallel()
+
+	combined := progress.NewCombined(3, progress.ParseLine)
+
+	// the streams which have not reported yet keep the combined progress from completing
+	p, err := combined.ParseLine("[stream 1] total size is 10,000  speedup is 1.00")
+	require.NoError(t, err)
+	assert.Equal(t, 99, p.Percentage)
+}
@@ -8,6 +8,7 @@ import (
 	"io"
 	"log/slog"
 	"os"
+	"time"
 
 	"github.com/schollz/progressbar/v3"
 	"golang.org/x/sync/errgroup"
@@ -15,17 +16,48 @@ import (
 
 type LogStreamFunc func(ctx context.Context) (io.ReadCloser, error)
 
+// ParseLineFunc parses a line in the output of a transfer into its progress.
+type ParseLineFunc func(line string) (Progress, error)
+
+// ChangeFunc records a change of a file on the destination.
+type ChangeFunc func(change Change) error
+
+const (
+	// drainTimeout is how long the remaining lines of the logs are waited for after the transfer completes.
+	drainTimeout = 2 * time.Second
+	// logStreamRetryInterval is the wait before streaming the logs again after the stream breaks.
+	logStreamRetryInterval = 1 * time.Second
+)
+
+var errLogStreamEnded = errors.New("log stream ended")
+
 type Logger struct {
 	options   LoggerOptions
 	successCh chan struct{}
+	stats     StatsCollector
+	// handledLines is the number of lines handled so far, to skip them if the logs are streamed again.
+	handledLines int
+	// streamLines is the number of lines received from the current stream of the logs.
+	streamLines int
 }
 
 type LoggerOptions struct {
 	ShowProgressBar bool
 	LogStreamFunc   LogStreamFunc
+	// ParseLineFunc defaults to ParseLine, which parses the output of rsync.
+	ParseLineFunc ParseLineFunc
+	// ChangeFunc is called with the changes of the files in the output of rsync with ChangeOutFormat, if not nil.
+	ChangeFunc ChangeFunc
+	// Restreams is true if LogStreamFunc streams the logs from their start on every call,
+	// so that the lines handled before it is called again are skipped.
+	Restreams bool
 }
 
 func NewLogger(options LoggerOptions) *Logger {
+	if options.ParseLineFunc == nil {
+		options.ParseLineFunc = ParseLine
+	}
+
 	return &Logger{
 		options:   options,
 		successCh: make(chan struct{}, 1),
@@ -40,6 +72,12 @@ func (l *Logger) Start(ctx context.Context, logger *slog.Logger) error {
 		}
 
 		logger.Debug("log tail failed, retrying", "error", err)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(logStreamRetryInterval):
+		}
 	}
 }
 
@@ -53,6 +91,11 @@ func (l *Logger) MarkAsComplete(ctx context.Context) error {
 	return nil
 }
 
+// Stats returns the stats of the transfer reported in the logs, or false if no stats were reported.
+func (l *Logger) Stats() (Stats, bool) {
+	return l.stats.Stats()
+}
+
 func (l *Logger) startSingle(ctx context.Context, logger *slog.Logger) error {
 	logCh := make(chan string)
 
@@ -78,13 +121,18 @@ func (l *Logger) startSingle(ctx context.Context, logger *slog.Logger) error {
 		return tailLogs(ctx, logStream, logCh)
 	})
 
+	// the logs are handled until the transfer is complete, which is when the tail is not needed anymore either
+	var handleErr error
+
 	eg.Go(func() error {
 		defer cancel()
 
-		return handleLogs(ctx, logCh, l.successCh, l.options.ShowProgressBar, logger)
+		handleErr = l.handleLogs(ctx, logCh, logger)
+
+		return handleErr
 	})
 
-	if err = eg.Wait(); err != nil {
+	if err = eg.Wait(); err != nil && handleErr != nil {
 		return fmt.Errorf("failed to wait for log tailing: %w", err)
 	}
 
@@ -99,44 +147,42 @@ func tailLogs(ctx context.Context, stream io.Reader, logCh chan<- string) error
 		case <-ctx.Done():
 			return ctx.Err() //nolint:wrapcheck
 		default:
-			if scanner.Scan() {
-				select {
-				case <-ctx.Done():
-					return ctx.Err() //nolint:wrapcheck
-				case logCh <- scanner.Text():
+			if !scanner.Scan() {
+				if err := scanner.Err(); err != nil {
+					return fmt.Errorf("failed to read log stream: %w", err)
 				}
+
+				return errLogStreamEnded
+			}
+
+			select {
+			case <-ctx.Done():
+				return ctx.Err() //nolint:wrapcheck
+			case logCh <- scanner.Text():
 			}
 		}
 	}
 }
 
-//nolint:cyclop
-func handleLogs(ctx context.Context, logCh <-chan string, successCh <-chan struct{},
-	showProgressBar bool, logger *slog.Logger,
-) error {
+func (l *Logger) handleLogs(ctx context.Context, logCh <-chan string, logger *slog.Logger) error {
 	var progressBar *progressbar.ProgressBar
 
-	if showProgressBar {
-		progressBar = progressbar.NewOptions64(
-			1,
-			progressbar.OptionSetWriter(os.Stderr),
-			progressbar.OptionEnableColorCodes(true),
-			progressbar.OptionShowBytes(true),
-			progressbar.OptionSetRenderBlankState(true),
-			progressbar.OptionFullWidth(),
-			progressbar.OptionOnCompletion(func() {
-				fmt.Fprintln(os.Stderr)
-			}),
-			progressbar.OptionSetDescription("📂 Copying data..."),
-		)
+	if l.options.ShowProgressBar {
+		progressBar = NewBar(1, "📂 Copying data...")
 	}
 
+	l.streamLines = 0
+
 	for {
 		select {
 		case <-ctx.Done():
 			return ctx.Err() //nolint:wrapcheck
-		case <-successCh:
-			if showProgressBar {
+		case <-l.successCh:
+			if l.options.ChangeFunc != nil {
+				l.drainLogs(ctx, logCh, progressBar, logger)
+			}
+
+			if l.options.ShowProgressBar {
 				if err := progressBar.Finish(); err != nil {
 					logger.Debug("failed to finish progress bar", "error", err)
 				}
@@ -144,27 +190,94 @@ func handleLogs(ctx context.Context, logCh <-chan string, successCh <-chan struc
 
 			return nil
 		case logLine := <-logCh:
-			progress, err := ParseLine(logLine)
-			if err != nil {
-				logger.Log(ctx, slog.LevelDebug-1, "failed to parse progress line", "error", err)
-
-				continue
+			if l.handleLine(ctx, logLine, progressBar, logger) {
+				return nil
 			}
+		}
+	}
+}
 
-			if !showProgressBar {
-				logger.Debug(logLine, slog.String("source", "rsync"), slog.Group("progress", "transferred",
-					progress.Transferred, "total", progress.Total, "percentage", progress.Percentage))
-			} else {
-				if err = updateProgressBar(progressBar, progress.Transferred, progress.Total); err != nil {
-					logger.Warn("failed to update progress bar", "error", err, "progress", progress)
-				}
+// drainLogs handles the remaining lines of the logs after the transfer completes, until no more lines arrive
+// for a while, so that no changes are missed.
+func (l *Logger) drainLogs(ctx context.Context, logCh <-chan string, progressBar *progressbar.ProgressBar,
+	logger *slog.Logger,
+) {
+	timer := time.NewTimer(drainTimeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			return
+		case logLine := <-logCh:
+			if l.handleLine(ctx, logLine, progressBar, logger) {
+				return
 			}
 
-			if progress.Percentage >= 100 { //nolint:mnd
-				return nil
+			timer.Reset(drainTimeout)
+		}
+	}
+}
+
+// handleLine handles a line of the logs, returning true if the transfer is complete.
+func (l *Logger) handleLine(ctx context.Context, logLine string, progressBar *progressbar.ProgressBar,
+	logger *slog.Logger,
+) bool {
+	if l.streamLines++; l.options.Restreams && l.streamLines <= l.handledLines {
+		return false
+	}
+
+	l.handledLines = max(l.handledLines, l.streamLines)
+
+	if l.stats.ParseLine(logLine) {
+		return false
+	}
+
+	if l.options.ChangeFunc != nil {
+		if change, ok := ParseChange(logLine); ok {
+			if err := l.options.ChangeFunc(change); err != nil {
+				logger.Warn("failed to record the change of a file", "error", err, "path", change.Path)
 			}
+
+			return false
 		}
 	}
+
+	progress, err := l.options.ParseLineFunc(logLine)
+	if err != nil {
+		logger.Log(ctx, slog.LevelDebug-1, "failed to parse progress line", "error", err)
+
+		return false
+	}
+
+	if progressBar == nil {
+		logger.Debug(logLine, slog.String("source", "rsync"), slog.Group("progress", "transferred",
+			progress.Transferred, "total", progress.Total, "percentage", progress.Percentage))
+	} else if err = updateProgressBar(progressBar, progress.Transferred, progress.Total); err != nil {
+		logger.Warn("failed to update progress bar", "error", err, "progress", progress)
+	}
+
+	return progress.Percentage >= 100 //nolint:mnd
+}
+
+// NewBar creates a progress bar which displays the progress of copying the given total number of bytes.
+//
+// A total of -1 means that the total is unknown, in which case a spinner is displayed instead.
+func NewBar(total int64, description string) *progressbar.ProgressBar {
+	return progressbar.NewOptions64(
+		total,
+		progressbar.OptionSetWriter(os.Stderr),
+		progressbar.OptionEnableColorCodes(true),
+		progressbar.OptionShowBytes(true),
+		progressbar.OptionSetRenderBlankState(true),
+		progressbar.OptionFullWidth(),
+		progressbar.OptionOnCompletion(func() {
+			fmt.Fprintln(os.Stderr)
+		}),
+		progressbar.OptionSetDescription(description),
+	)
 }
 
 func updateProgressBar(progressBar *progressbar.ProgressBar, transferred, total int64) error {
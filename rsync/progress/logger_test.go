@@ -0,0 +1,69 @@
+package progress_test
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/neilotoole/slogt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/utkuozdemir/pv-migrate/rsync/progress"
+)
+
+func TestLoggerChanges(t *testing.T) {
+	t.Parallel()
+
+	const logs = "[change] >f+++++++++ a\n" +
+		"[change] *deleting   b\n" +
+		"total size is 10,000  speedup is 1.00\n"
+
+	var (
+		changes []progress.Change
+		streams int
+	)
+
+	logger := progress.NewLogger(progress.LoggerOptions{
+		LogStreamFunc: func(context.Context) (io.ReadCloser, error) {
+			streams++
+
+			// the first stream breaks after the first line, the second one starts from the beginning
+			if streams == 1 {
+				return io.NopCloser(&failingReader{data: strings.SplitAfter(logs, "\n")[0]}), nil
+			}
+
+			return io.NopCloser(strings.NewReader(logs)), nil
+		},
+		ChangeFunc: func(change progress.Change) error {
+			changes = append(changes, change)
+
+			return nil
+		},
+		Restreams: true,
+	})
+
+	require.NoError(t, logger.Start(context.Background(), slogt.New(t)))
+
+	assert.Equal(t, []progress.Change{
+		{Action: progress.ChangeCreated, Itemized: ">f+++++++++", Path: "a"},
+		{Action: progress.ChangeDeleted, Itemized: "*deleting", Path: "b"},
+	}, changes)
+}
+
+// failingReader returns its data, then an error.
+type failingReader struct {
+	data string
+	read bool
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if r.read {
+		return 0, io.ErrUnexpectedEOF
+	}
+
+	r.read = true
+
+	return copy(p, r.data), nil
+}
@@ -0,0 +1,162 @@
+package progress
+
+import (
+	"log/slog"
+	"regexp"
+	"strconv"
+	"sync"
+)
+
+var statsLineRegex = regexp.MustCompile(`^\s*([A-Za-z ]+): ([0-9]+(,[0-9]+)*)\b`)
+
+// Stats is the summary of a transfer, as reported by rsync --stats.
+type Stats struct {
+	// Files is the number of files in the transfer, including the directories, symlinks etc.
+	Files int64
+	// CreatedFiles is the number of files created on the destination.
+	CreatedFiles int64
+	// DeletedFiles is the number of files deleted from the destination.
+	DeletedFiles int64
+	// TransferredFiles is the number of regular files which were updated.
+	TransferredFiles int64
+	// TotalSize is the total size of the files in the transfer, in bytes.
+	TotalSize int64
+	// TransferredSize is the total size of the files which were updated, in bytes.
+	TransferredSize int64
+	// LiteralData is the number of bytes which were sent as they are.
+	LiteralData int64
+	// MatchedData is the number of bytes which were reused from the existing files on the destination.
+	MatchedData int64
+	// BytesSent and BytesReceived are the number of bytes sent and received by rsync, including the protocol overhead.
+	BytesSent     int64
+	BytesReceived int64
+}
+
+// Speedup returns the ratio of the total size of the files to the bytes sent and received, as rsync computes it.
+func (s Stats) Speedup() float64 {
+	if s.BytesSent+s.BytesReceived == 0 {
+		return 0
+	}
+
+	return float64(s.TotalSize) / float64(s.BytesSent+s.BytesReceived)
+}
+
+func (s Stats) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.Int64("files", s.Files),
+		slog.Int64("created_files", s.CreatedFiles),
+		slog.Int64("deleted_files", s.DeletedFiles),
+		slog.Int64("transferred_files", s.TransferredFiles),
+		slog.Int64("total_size", s.TotalSize),
+		slog.Int64("transferred_size", s.TransferredSize),
+		slog.Int64("literal_data", s.LiteralData),
+		slog.Int64("matched_data", s.MatchedData),
+		slog.String("speedup", strconv.FormatFloat(s.Speedup(), 'f', 2, 64)), //nolint:mnd
+	)
+}
+
+// StatsCollector collects the stats of a transfer from the output lines of rsync --stats.
+//
+// The stats of the streams of a parallel transfer, prefixed with StreamPrefixFormat, are summed up.
+// If a stream reports its stats more than once, e.g. when the transfer is retried, the last ones are used.
+type StatsCollector struct {
+	mu      sync.Mutex
+	streams map[int]*Stats
+}
+
+// ParseLine parses the line into the stats, returning false if it is not a line of the stats.
+func (c *StatsCollector) ParseLine(line string) bool {
+	stream := 0
+
+	if matches := streamLineRegex.FindStringSubmatch(line); matches != nil {
+		var err error
+		if stream, err = strconv.Atoi(matches[1]); err != nil {
+			return false
+		}
+
+		line = matches[2]
+	}
+
+	matches := statsLineRegex.FindStringSubmatch(line)
+	if matches == nil {
+		return false
+	}
+
+	value, err := parseNumBytes(matches[2])
+	if err != nil {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.streams == nil {
+		c.streams = make(map[int]*Stats)
+	}
+
+	stats, ok := c.streams[stream]
+	if !ok {
+		stats = &Stats{}
+	}
+
+	field := statsField(stats, matches[1])
+	if field == nil {
+		return false
+	}
+
+	*field = value
+	c.streams[stream] = stats
+
+	return true
+}
+
+// Stats returns the stats of the transfer, or false if no stats were reported.
+func (c *StatsCollector) Stats() (Stats, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var total Stats
+
+	for _, stats := range c.streams {
+		total.Files += stats.Files
+		total.CreatedFiles += stats.CreatedFiles
+		total.DeletedFiles += stats.DeletedFiles
+		total.TransferredFiles += stats.TransferredFiles
+		total.TotalSize += stats.TotalSize
+		total.TransferredSize += stats.TransferredSize
+		total.LiteralData += stats.LiteralData
+		total.MatchedData += stats.MatchedData
+		total.BytesSent += stats.BytesSent
+		total.BytesReceived += stats.BytesReceived
+	}
+
+	return total, len(c.streams) > 0
+}
+
+// statsField returns the field of the stats with the given label in the output of rsync --stats.
+func statsField(stats *Stats, label string) *int64 {
+	switch label {
+	case "Number of files":
+		return &stats.Files
+	case "Number of created files":
+		return &stats.CreatedFiles
+	case "Number of deleted files":
+		return &stats.DeletedFiles
+	case "Number of regular files transferred":
+		return &stats.TransferredFiles
+	case "Total file size":
+		return &stats.TotalSize
+	case "Total transferred file size":
+		return &stats.TransferredSize
+	case "Literal data":
+		return &stats.LiteralData
+	case "Matched data":
+		return &stats.MatchedData
+	case "Total bytes sent":
+		return &stats.BytesSent
+	case "Total bytes received":
+		return &stats.BytesReceived
+	default:
+		return nil
+	}
+}
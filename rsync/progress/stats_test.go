@@ -0,0 +1,79 @@
+package progress_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/utkuozdemir/pv-migrate/rsync/progress"
+)
+
+const testStatsOutput = `Number of files: 1,234 (reg: 1,000, dir: 234)
+Number of created files: 10 (reg: 10)
+Number of deleted files: 2 (reg: 2)
+Number of regular files transferred: 5
+Total file size: 1,879,048,192 bytes
+Total transferred file size: 100,000 bytes
+Literal data: 60,000 bytes
+Matched data: 40,000 bytes
+File list size: 1,234
+File list generation time: 0.001 seconds
+File list transfer time: 0.000 seconds
+Total bytes sent: 50,000
+Total bytes received: 2,000
+
+sent 50,000 bytes  received 2,000 bytes  104,000.00 bytes/sec
+total size is 1,879,048,192  speedup is 36,135.54`
+
+func TestStatsCollector(t *testing.T) {
+	t.Parallel()
+
+	var collector progress.StatsCollector
+
+	_, ok := collector.Stats()
+	assert.False(t, ok)
+
+	var parsed []string
+
+	for _, line := range strings.Split(testStatsOutput, "\n") {
+		if collector.ParseLine(line) {
+			parsed = append(parsed, line)
+		}
+	}
+
+	assert.Len(t, parsed, 10)
+
+	stats, ok := collector.Stats()
+	require.True(t, ok)
+	assert.Equal(t, progress.Stats{
+		Files:            1234,
+		CreatedFiles:     10,
+		DeletedFiles:     2,
+		TransferredFiles: 5,
+		TotalSize:        1879048192,
+		TransferredSize:  100000,
+		LiteralData:      60000,
+		MatchedData:      40000,
+		BytesSent:        50000,
+		BytesReceived:    2000,
+	}, stats)
+	assert.InDelta(t, 36135.54, stats.Speedup(), 0.01)
+}
+
+func TestStatsCollectorStreams(t *testing.T) {
+	t.Parallel()
+
+	var collector progress.StatsCollector
+
+	assert.True(t, collector.ParseLine("[stream 0] Number of regular files transferred: 5"))
+	assert.True(t, collector.ParseLine("[stream 1] Number of regular files transferred: 3"))
+	// a retried stream reports its stats again, replacing the previous ones
+	assert.True(t, collector.ParseLine("[stream 1] Number of regular files transferred: 4"))
+	assert.False(t, collector.ParseLine("[stream 1]       1,000  10%  1.00MB/s    0:00:09"))
+
+	stats, ok := collector.Stats()
+	require.True(t, ok)
+	assert.Equal(t, int64(9), stats.TransferredFiles)
+}
@@ -0,0 +1,35 @@
+package rsync
+
+import (
+	"regexp"
+	"strings"
+)
+
+// shellSafeRegex matches the words which are passed as they are by a POSIX shell, so they need no quoting.
+var shellSafeRegex = regexp.MustCompile(`^[a-zA-Z0-9_@%+=:,./-]+$`)
+
+// shellQuote quotes the string to be used as a single word in a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// shellJoin renders the arguments into a POSIX shell command which runs them as they are,
+// quoting the ones which are not safe to be used as a single word.
+func shellJoin(args []string) string {
+	words := make([]string, 0, len(args))
+
+	for _, arg := range args {
+		switch {
+		case shellSafeRegex.MatchString(arg):
+		case strings.Contains(arg, "'") && !strings.ContainsAny(arg, "\"$`\\"):
+			// nothing but these is special in double quotes, which read better than the escaped single quotes
+			arg = `"` + arg + `"`
+		default:
+			arg = shellQuote(arg)
+		}
+
+		words = append(words, arg)
+	}
+
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,172 @@
+package rsync
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultSSHConnectTimeout is the timeout of establishing the ssh connection if SSHOptions.ConnectTimeout is zero.
+const DefaultSSHConnectTimeout = 5 * time.Second
+
+var (
+	sshJumpHostRegex  = regexp.MustCompile(`^([a-zA-Z0-9_][a-zA-Z0-9._-]*@)?([a-zA-Z0-9][a-zA-Z0-9.-]*|\[[0-9a-fA-F:.]+\])(:[0-9]+)?$`)
+	sshCipherRegex    = regexp.MustCompile(`^[a-z0-9][a-z0-9.@-]*$`)
+	sshOptionKeyRegex = regexp.MustCompile(`^[a-zA-Z]+$`)
+)
+
+// SSHOptions are the options of the ssh connection of the transfer.
+type SSHOptions struct {
+	// ProxyJump are the [user@]host[:port] hosts to jump through to reach the remote, in order.
+	ProxyJump []string
+	// Ciphers are the ciphers allowed for the connection, in order of preference. Defaults of ssh are used if empty.
+	Ciphers []string
+	// ConnectTimeout is the timeout of establishing the connection, DefaultSSHConnectTimeout if zero.
+	ConnectTimeout time.Duration
+	// ServerAliveInterval is the interval of the keepalive messages sent to the remote, disabled if zero.
+	ServerAliveInterval time.Duration
+	// ExtraOptions are additional options in Key=Value form, as passed to the -o flag of ssh.
+	// They take precedence over the options set by pv-migrate.
+	ExtraOptions []string
+}
+
+// Validate returns an error if any of the options is invalid or cannot be safely rendered into a shell command.
+func (o *SSHOptions) Validate() error {
+	for _, host := range o.ProxyJump {
+		if !sshJumpHostRegex.MatchString(host) {
+			return fmt.Errorf("invalid ssh proxy jump host, expected [user@]host[:port]: %q", host)
+		}
+	}
+
+	for _, cipher := range o.Ciphers {
+		if !sshCipherRegex.MatchString(cipher) {
+			return fmt.Errorf("invalid ssh cipher: %q", cipher)
+		}
+	}
+
+	if o.ConnectTimeout < 0 {
+		return fmt.Errorf("ssh connect timeout cannot be negative: %s", o.ConnectTimeout)
+	}
+
+	if o.ServerAliveInterval < 0 {
+		return fmt.Errorf("ssh keepalive interval cannot be negative: %s", o.ServerAliveInterval)
+	}
+
+	for _, option := range o.ExtraOptions {
+		key, value, ok := strings.Cut(option, "=")
+		if !ok || !sshOptionKeyRegex.MatchString(key) || strings.TrimSpace(value) == "" {
+			return fmt.Errorf("invalid ssh option, expected Key=Value: %q", option)
+		}
+
+		// the options are rendered in single quotes into the remote shell command of rsync, which does not support
+		// escaping them, and into the shell commands of the other engines
+		if strings.ContainsAny(value, "'\"`$\\\n") {
+			return fmt.Errorf("ssh option cannot contain quotes, backslashes, dollar signs or newlines: %q", option)
+		}
+	}
+
+	return nil
+}
+
+// Args returns the ssh arguments for the options, ready to be used in a shell command.
+func (o *SSHOptions) Args() []string {
+	args := make([]string, 0, 2*len(o.ExtraOptions)) //nolint:mnd
+
+	// ssh uses the first value obtained for an option, so the extra options come first to be able to override
+	for _, option := range o.ExtraOptions {
+		if strings.ContainsAny(option, " \t") {
+			option = "'" + option + "'"
+		}
+
+		args = append(args, "-o", option)
+	}
+
+	args = append(args, "-o", "StrictHostKeyChecking=no", "-o", "UserKnownHostsFile=/dev/null")
+
+	connectTimeout := o.ConnectTimeout
+	if connectTimeout == 0 {
+		connectTimeout = DefaultSSHConnectTimeout
+	}
+
+	args = append(args, "-o", "ConnectTimeout="+seconds(connectTimeout))
+
+	if o.ServerAliveInterval > 0 {
+		args = append(args, "-o", "ServerAliveInterval="+seconds(o.ServerAliveInterval))
+	}
+
+	if len(o.Ciphers) > 0 {
+		args = append(args, "-c", strings.Join(o.Ciphers, ","))
+	}
+
+	if len(o.ProxyJump) > 0 {
+		args = append(args, "-J", strings.Join(o.ProxyJump, ","))
+	}
+
+	return args
+}
+
+// ClientConfig returns the content of an ssh_config(5) file for the options, authenticating with the given
+// identity file, or an empty string if there are no jump hosts. The jump hosts are authenticated with
+// proxyJumpIdentityFile first, if it is not empty.
+//
+// The options on the command line only apply to the remote and not to the jump hosts,
+// so the config file is needed to connect to the jump hosts with the same options.
+func (o *SSHOptions) ClientConfig(identityFile, proxyJumpIdentityFile string) string {
+	if len(o.ProxyJump) == 0 {
+		return ""
+	}
+
+	var config strings.Builder
+
+	// ssh uses all the identity files obtained for a host, in order, so the ones of the jump hosts come first
+	if proxyJumpIdentityFile != "" {
+		hosts := make([]string, 0, len(o.ProxyJump))
+		for _, jumpHost := range o.ProxyJump {
+			if match := sshJumpHostRegex.FindStringSubmatch(jumpHost); match != nil {
+				hosts = append(hosts, strings.TrimSuffix(strings.TrimPrefix(match[2], "["), "]"))
+			}
+		}
+
+		config.WriteString("Host " + strings.Join(hosts, " ") + "\n")
+		config.WriteString("  IdentityFile " + proxyJumpIdentityFile + "\n")
+	}
+
+	config.WriteString("Host *\n")
+
+	for _, option := range o.ExtraOptions {
+		key, value, _ := strings.Cut(option, "=")
+		config.WriteString("  " + key + " " + value + "\n")
+	}
+
+	config.WriteString("  StrictHostKeyChecking no\n")
+	config.WriteString("  UserKnownHostsFile /dev/null\n")
+
+	if identityFile != "" {
+		config.WriteString("  IdentityFile " + identityFile + "\n")
+	}
+
+	connectTimeout := o.ConnectTimeout
+	if connectTimeout == 0 {
+		connectTimeout = DefaultSSHConnectTimeout
+	}
+
+	config.WriteString("  ConnectTimeout " + seconds(connectTimeout) + "\n")
+
+	if o.ServerAliveInterval > 0 {
+		config.WriteString("  ServerAliveInterval " + seconds(o.ServerAliveInterval) + "\n")
+	}
+
+	if len(o.Ciphers) > 0 {
+		config.WriteString("  Ciphers " + strings.Join(o.Ciphers, ",") + "\n")
+	}
+
+	return config.String()
+}
+
+// seconds returns the duration in whole seconds for ssh, rounded up.
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
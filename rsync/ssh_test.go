@@ -0,0 +1,57 @@
+package rsync
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSSHOptionsValidate(t *testing.T) {
+	t.Parallel()
+
+	valid := SSHOptions{
+		ProxyJump:    []string{"bastion", "user@bastion.example.com:22", "[2001:db8::1]:2222"},
+		Ciphers:      []string{"aes256-ctr"},
+		ExtraOptions: []string{"ProxyCommand=nc -X 5 -x proxy:1080 %h %p"},
+	}
+	require.NoError(t, valid.Validate())
+
+	for _, invalid := range []SSHOptions{
+		{ProxyJump: []string{"user@bastion;reboot"}},
+		{ProxyJump: []string{"-oProxyCommand=reboot"}},
+		{ProxyJump: []string{"-oVisualHostKey"}},
+		{Ciphers: []string{"aes256-ctr -oProxyCommand=reboot"}},
+		{ConnectTimeout: -time.Second},
+		{ServerAliveInterval: -time.Second},
+		{ExtraOptions: []string{"Compression"}},
+		{ExtraOptions: []string{"Compression="}},
+		{ExtraOptions: []string{"-J=bastion"}},
+		{ExtraOptions: []string{"ProxyCommand=sh -c 'reboot'"}},
+		{ExtraOptions: []string{"ProxyCommand=$(reboot)"}},
+	} {
+		assert.Error(t, invalid.Validate(), "%+v", invalid)
+	}
+}
+
+func TestSSHOptionsClientConfig(t *testing.T) {
+	t.Parallel()
+
+	assert.Empty(t, (&SSHOptions{Ciphers: []string{"aes256-ctr"}}).ClientConfig("/tmp/id_ed25519", ""))
+
+	opts := SSHOptions{
+		ProxyJump:           []string{"bastion"},
+		ServerAliveInterval: 15 * time.Second,
+		ExtraOptions:        []string{"LogLevel=ERROR"},
+	}
+	assert.Equal(t, "Host *\n  LogLevel ERROR\n  StrictHostKeyChecking no\n  UserKnownHostsFile /dev/null\n"+
+		"  IdentityFile /tmp/id_ed25519\n  ConnectTimeout 5\n  ServerAliveInterval 15\n",
+		opts.ClientConfig("/tmp/id_ed25519", ""))
+
+	opts.ProxyJump = []string{"jump@bastion:2222", "[2001:db8::1]"}
+	assert.Equal(t, "Host bastion 2001:db8::1\n  IdentityFile /tmp/id_proxy\nHost *\n  LogLevel ERROR\n"+
+		"  StrictHostKeyChecking no\n  UserKnownHostsFile /dev/null\n  IdentityFile /tmp/id_ed25519\n"+
+		"  ConnectTimeout 5\n  ServerAliveInterval 15\n",
+		opts.ClientConfig("/tmp/id_ed25519", "/tmp/id_proxy"))
+}
@@ -0,0 +1,182 @@
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const dialTimeout = 10 * time.Second
+
+// ReverseTunnelRequest describes a command to be run on a remote sshd
+// while a port on the remote is forwarded back to an address reachable from here (like `ssh -R`).
+type ReverseTunnelRequest struct {
+	// Addr is the address of the sshd to connect to.
+	Addr       string
+	User       string
+	PrivateKey string
+	// RemotePort is the port to listen on at the remote side.
+	RemotePort int
+	// TargetAddr is the address the connections to the remote port are forwarded to.
+	TargetAddr string
+	Command    string
+	Stdout     io.Writer
+	Stderr     io.Writer
+}
+
+// CommandError is returned when the remote command was run but did not succeed.
+type CommandError struct {
+	ExitStatus int
+	Signal     string
+	Stderr     string
+}
+
+func (e *CommandError) Error() string {
+	msg := "remote command exited with status " + strconv.Itoa(e.ExitStatus)
+	if e.Signal != "" {
+		msg += " (signal " + e.Signal + ")"
+	}
+
+	if e.Stderr != "" {
+		msg += ": " + e.Stderr
+	}
+
+	return msg
+}
+
+// RunWithReverseTunnel connects to the sshd, sets up the remote port forwarding
+// and runs the command on the remote, blocking until the command exits.
+func RunWithReverseTunnel(ctx context.Context, req *ReverseTunnelRequest, logger *slog.Logger) error {
+	signer, err := ssh.ParsePrivateKey([]byte(req.PrivateKey))
+	if err != nil {
+		return fmt.Errorf("failed to parse private key: %w", err)
+	}
+
+	client, err := ssh.Dial("tcp", req.Addr, &ssh.ClientConfig{
+		User: req.User,
+		Auth: []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		// the sshd runs in a pod created for this transfer with a freshly generated host key
+		// and is reached over an authenticated port-forward, so there is no known host key to check against
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(), //nolint:gosec
+		Timeout:         dialTimeout,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to connect to sshd at %s: %w", req.Addr, err)
+	}
+
+	defer func() { _ = client.Close() }()
+
+	listener, err := client.Listen("tcp", net.JoinHostPort("localhost", strconv.Itoa(req.RemotePort)))
+	if err != nil {
+		return fmt.Errorf("failed to request remote port forwarding on port %d: %w", req.RemotePort, err)
+	}
+
+	defer func() { _ = listener.Close() }()
+
+	go forwardConnections(listener, req.TargetAddr, logger)
+
+	session, err := client.NewSession()
+	if err != nil {
+		return fmt.Errorf("failed to open ssh session: %w", err)
+	}
+
+	defer func() { _ = session.Close() }()
+
+	var stderr bytes.Buffer
+
+	session.Stdout = req.Stdout
+	session.Stderr = &stderr
+
+	if req.Stderr != nil {
+		session.Stderr = io.MultiWriter(req.Stderr, &stderr)
+	}
+
+	errCh := make(chan error, 1)
+
+	go func() { errCh <- session.Run(req.Command) }()
+
+	select {
+	case <-ctx.Done():
+		_ = client.Close()
+
+		return ctx.Err() //nolint:wrapcheck
+	case err = <-errCh:
+	}
+
+	var exitErr *ssh.ExitError
+	if errors.As(err, &exitErr) {
+		return &CommandError{
+			ExitStatus: exitErr.ExitStatus(),
+			Signal:     exitErr.Signal(),
+			Stderr:     strings.TrimSpace(stderr.String()),
+		}
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to run remote command: %w", err)
+	}
+
+	return nil
+}
+
+func forwardConnections(listener net.Listener, targetAddr string, logger *slog.Logger) {
+	for {
+		remoteConn, err := listener.Accept()
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				logger.Debug("stopped accepting forwarded connections", "error", err)
+			}
+
+			return
+		}
+
+		go func() {
+			if err := forwardConnection(remoteConn, targetAddr); err != nil {
+				logger.Warn("🔶 Failed to forward connection", "target", targetAddr, "error", err)
+			}
+		}()
+	}
+}
+
+func forwardConnection(remoteConn net.Conn, targetAddr string) error {
+	defer func() { _ = remoteConn.Close() }()
+
+	localConn, err := net.DialTimeout("tcp", targetAddr, dialTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to dial %s: %w", targetAddr, err)
+	}
+
+	defer func() { _ = localConn.Close() }()
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		_, _ = io.Copy(localConn, remoteConn)
+
+		if tcpConn, ok := localConn.(*net.TCPConn); ok {
+			_ = tcpConn.CloseWrite()
+		}
+	}()
+
+	_, _ = io.Copy(remoteConn, localConn)
+
+	_ = remoteConn.Close()
+
+	wg.Wait()
+
+	return nil
+}
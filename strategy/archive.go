@@ -0,0 +1,329 @@
+package strategy
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/utkuozdemir/pv-migrate/archive"
+	"github.com/utkuozdemir/pv-migrate/k8s"
+	"github.com/utkuozdemir/pv-migrate/migration"
+	"github.com/utkuozdemir/pv-migrate/pvc"
+	"github.com/utkuozdemir/pv-migrate/rsync/progress"
+	"github.com/utkuozdemir/pv-migrate/transfer"
+)
+
+const (
+	helperContainerName = "sshd"
+
+	kibibyte = 1024
+)
+
+// Archive streams the data in the source PVC of the attempt as a tar archive into the given file.
+//
+// The tar stream is produced by a helper pod which mounts the PVC and is read over the exec API.
+func Archive(ctx context.Context, attempt *migration.Attempt, outputPath, compression string,
+	logger *slog.Logger,
+) error {
+	mig := attempt.Migration
+	sourceInfo := mig.SourceInfo
+	readOnly := mig.Request.SourceMountReadOnly
+
+	releaseNames := []string{attempt.HelmReleaseNamePrefix}
+
+	doneCh := registerCleanupHook(attempt, releaseNames, logger)
+	defer cleanupAndReleaseHook(ctx, attempt, releaseNames, doneCh, logger)
+
+	pod, err := installHelperPod(ctx, attempt, sourceInfo, attempt.HelmReleaseNamePrefix, readOnly, logger)
+	if err != nil {
+		return err
+	}
+
+	dir := path.Join(localDirMountPath, mig.Request.Source.Path)
+
+	total, err := estimateSize(ctx, sourceInfo, pod, dir, mig.Request.Excludes)
+	if err != nil {
+		logger.Warn("🔶 Failed to estimate the size of the data, progress will not be accurate", "error", err)
+
+		total = -1
+	}
+
+	partialPath := outputPath + ".partial"
+
+	file, err := os.Create(partialPath)
+	if err != nil {
+		return fmt.Errorf("failed to create archive file: %w", err)
+	}
+
+	defer func() {
+		_ = file.Close()
+		_ = os.Remove(partialPath)
+	}()
+
+	manifest := archive.Manifest{
+		Version:   archive.ManifestVersion,
+		CreatedAt: time.Now().UTC(),
+		Namespace: sourceInfo.Claim.Namespace,
+		Name:      sourceInfo.Claim.Name,
+		Path:      mig.Request.Source.Path,
+		Spec:      sourceInfo.Claim.Spec,
+	}
+
+	logger.Info("📦 Archiving data", "output", outputPath, "compression", compression)
+
+	reader, writer := io.Pipe()
+	tarCmd := tarCreateCmd(dir, mig.Request.Excludes)
+
+	var (
+		eg       errgroup.Group //nolint:varnamelen
+		checksum string
+	)
+
+	eg.Go(func() error {
+		err := execInPod(ctx, sourceInfo, pod, tarCmd, nil, writer)
+		writer.CloseWithError(err)
+
+		return err
+	})
+
+	progressReader, finishProgress := newProgressReader(ctx, mig, reader, total, "📦 Archiving data...")
+	defer finishProgress()
+
+	eg.Go(func() error {
+		var err error
+
+		checksum, err = archive.Write(file, progressReader, &manifest, compression)
+		reader.CloseWithError(err)
+
+		return err //nolint:wrapcheck
+	})
+
+	if err = eg.Wait(); err != nil {
+		return fmt.Errorf("failed to archive data: %w", err)
+	}
+
+	if err = file.Close(); err != nil {
+		return fmt.Errorf("failed to close archive file: %w", err)
+	}
+
+	if err = os.Rename(partialPath, outputPath); err != nil {
+		return fmt.Errorf("failed to move archive into place: %w", err)
+	}
+
+	logger.Info("🔏 Archive written", "output", outputPath, "sha256", checksum)
+
+	return nil
+}
+
+// Restore extracts the data in the given archive into the destination PVC of the attempt.
+//
+// The archive is verified against its checksum before anything is written into the PVC.
+func Restore(ctx context.Context, attempt *migration.Attempt, inputPath string, logger *slog.Logger) error {
+	mig := attempt.Migration
+	destInfo := mig.DestInfo
+
+	file, err := os.Open(inputPath)
+	if err != nil {
+		return fmt.Errorf("failed to open archive file: %w", err)
+	}
+
+	defer func() { _ = file.Close() }()
+
+	logger.Info("🔍 Verifying archive", "input", inputPath)
+
+	manifest, err := archive.Verify(file)
+	if err != nil {
+		return fmt.Errorf("failed to verify archive: %w", err)
+	}
+
+	logger.Info("📜 Archive verified", "source", manifest.Namespace+"/"+manifest.Name,
+		"path", manifest.Path, "created_at", manifest.CreatedAt)
+
+	warnIfSmaller(manifest, destInfo.Claim, logger)
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat archive file: %w", err)
+	}
+
+	if _, err = file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to rewind archive file: %w", err)
+	}
+
+	releaseNames := []string{attempt.HelmReleaseNamePrefix}
+
+	doneCh := registerCleanupHook(attempt, releaseNames, logger)
+	defer cleanupAndReleaseHook(ctx, attempt, releaseNames, doneCh, logger)
+
+	pod, err := installHelperPod(ctx, attempt, destInfo, attempt.HelmReleaseNamePrefix, false, logger)
+	if err != nil {
+		return err
+	}
+
+	tarCmd := tarExtractCmd(path.Join(localDirMountPath, mig.Request.Dest.Path), mig.Request.NoChown)
+	reader, writer := io.Pipe()
+
+	var eg errgroup.Group //nolint:varnamelen
+
+	progressReader, finishProgress := newProgressReader(ctx, mig, file, fileInfo.Size(), "📂 Restoring data...")
+	defer finishProgress()
+
+	eg.Go(func() error {
+		_, err := archive.Extract(writer, progressReader)
+		writer.CloseWithError(err)
+
+		return err //nolint:wrapcheck
+	})
+
+	eg.Go(func() error {
+		err := execInPod(ctx, destInfo, pod, tarCmd, reader, io.Discard)
+		reader.CloseWithError(err)
+
+		return err
+	})
+
+	if err = eg.Wait(); err != nil {
+		return fmt.Errorf("failed to restore data: %w", err)
+	}
+
+	return nil
+}
+
+// installHelperPod installs a pod which mounts the PVC and does nothing, to be able to exec into it.
+func installHelperPod(ctx context.Context, attempt *migration.Attempt, pvcInfo *pvc.Info,
+	releaseName string, readOnly bool, logger *slog.Logger,
+) (*corev1.Pod, error) {
+	vals := map[string]any{
+		"sshd": map[string]any{
+			"enabled":        true,
+			"namespace":      pvcInfo.Claim.Namespace,
+			"publicKeyMount": false,
+			"pvcMounts": []map[string]any{
+				{
+					"name":      pvcInfo.Claim.Name,
+					"readOnly":  readOnly,
+					"mountPath": localDirMountPath,
+				},
+			},
+			"affinity": pvcInfo.AffinityHelmValues,
+		},
+	}
+
+	if err := installHelmChart(ctx, attempt, pvcInfo, releaseName, vals, logger); err != nil {
+		return nil, fmt.Errorf("failed to install helper pod: %w", err)
+	}
+
+	return getSshdPodForHelmRelease(ctx, pvcInfo, releaseName)
+}
+
+// tarCreateCmd returns the command which writes the contents of the directory as a tar stream into stdout,
+// skipping the files matching the excludes.
+func tarCreateCmd(dir string, excludes []string) []string {
+	cmd := append([]string{"tar", "-c", "-f", "-"}, transfer.TarExcludeArgs(excludes)...)
+
+	return append(cmd, "-C", dir, ".")
+}
+
+// tarExtractCmd returns the command which extracts the tar stream in stdin into the directory, creating it if needed.
+func tarExtractCmd(dir string, noChown bool) []string {
+	script := `mkdir -p "$0" && tar -x -f - -C "$0"`
+	if noChown {
+		script += " -o"
+	}
+
+	return []string{"sh", "-c", script, dir}
+}
+
+func execInPod(ctx context.Context, pvcInfo *pvc.Info, pod *corev1.Pod,
+	command []string, stdin io.Reader, stdout io.Writer,
+) error {
+	var stderr bytes.Buffer
+
+	if err := k8s.Exec(ctx, &k8s.ExecRequest{
+		RestConfig: pvcInfo.ClusterClient.RestConfig,
+		PodNs:      pod.Namespace,
+		PodName:    pod.Name,
+		Container:  helperContainerName,
+		Command:    command,
+		Stdin:      stdin,
+		Stdout:     stdout,
+		Stderr:     &stderr,
+	}); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+
+		return err //nolint:wrapcheck
+	}
+
+	return nil
+}
+
+// estimateSize returns the disk usage of the given directory in the pod in bytes, without the files matching
+// the excludes. du is run in the directory, so that it sees the same paths as tarCreateCmd.
+func estimateSize(ctx context.Context, pvcInfo *pvc.Info, pod *corev1.Pod, dir string,
+	excludes []string,
+) (int64, error) {
+	var stdout bytes.Buffer
+
+	cmd := append([]string{"sh", "-c", `cd "$0" && exec du -s -k "$@" .`, dir}, transfer.TarExcludeArgs(excludes)...)
+
+	if err := execInPod(ctx, pvcInfo, pod, cmd, nil, &stdout); err != nil {
+		return 0, err
+	}
+
+	fields := strings.Fields(stdout.String())
+	if len(fields) == 0 {
+		return 0, errors.New("unexpected du output: " + stdout.String())
+	}
+
+	kib, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse du output: %w", err)
+	}
+
+	return kib * kibibyte, nil
+}
+
+// newProgressReader wraps the reader to display the progress of reading it on a progress bar, if requested.
+// The returned function finishes the progress bar.
+func newProgressReader(ctx context.Context, mig *migration.Migration, reader io.Reader,
+	total int64, description string,
+) (io.Reader, func()) {
+	canDisplayProgressBar := ctx.Value(progress.CanDisplayProgressBarContextKey{}) != nil
+	if mig.Request.NoProgressBar || !canDisplayProgressBar {
+		return reader, func() {}
+	}
+
+	bar := progress.NewBar(total, description)
+
+	return io.TeeReader(reader, bar), func() { _ = bar.Finish() }
+}
+
+func warnIfSmaller(manifest *archive.Manifest, claim *corev1.PersistentVolumeClaim, logger *slog.Logger) {
+	sourceSize, ok := manifest.Spec.Resources.Requests[corev1.ResourceStorage]
+	if !ok {
+		return
+	}
+
+	destSize, ok := claim.Status.Capacity[corev1.ResourceStorage]
+	if !ok {
+		destSize, ok = claim.Spec.Resources.Requests[corev1.ResourceStorage]
+	}
+
+	if ok && destSize.Cmp(sourceSize) < 0 {
+		logger.Warn("🔶 Destination PVC is smaller than the archived PVC",
+			"source_size", sourceSize.String(), "dest_size", destSize.String())
+	}
+}
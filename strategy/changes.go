@@ -0,0 +1,46 @@
+package strategy
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/utkuozdemir/pv-migrate/migration"
+	"github.com/utkuozdemir/pv-migrate/rsync/progress"
+)
+
+// openChangesLog opens the changes log of the migration for appending, and returns the function which records
+// the changes of the files into it as JSON lines, and the function to close it.
+//
+// The returned change function is nil if the migration has no changes log.
+func openChangesLog(request *migration.Request) (progress.ChangeFunc, func(logger *slog.Logger), error) {
+	if request.ChangesLogPath == "" {
+		return nil, func(*slog.Logger) {}, nil
+	}
+
+	file, err := os.OpenFile(request.ChangesLogPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644) //nolint:mnd
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open changes log: %w", err)
+	}
+
+	encoder := json.NewEncoder(file)
+	// the itemized changes start with < and >, which are kept readable
+	encoder.SetEscapeHTML(false)
+
+	changeFunc := func(change progress.Change) error {
+		if err := encoder.Encode(change); err != nil {
+			return fmt.Errorf("failed to write to changes log: %w", err)
+		}
+
+		return nil
+	}
+
+	closeFunc := func(logger *slog.Logger) {
+		if err := file.Close(); err != nil {
+			logger.Warn("failed to close changes log", "path", request.ChangesLogPath, "error", err)
+		}
+	}
+
+	return changeFunc, closeFunc, nil
+}
@@ -0,0 +1,42 @@
+package strategy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/neilotoole/slogt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/utkuozdemir/pv-migrate/migration"
+	"github.com/utkuozdemir/pv-migrate/rsync/progress"
+)
+
+func TestOpenChangesLog(t *testing.T) {
+	t.Parallel()
+
+	changeFunc, closeFunc, err := openChangesLog(&migration.Request{})
+	require.NoError(t, err)
+	assert.Nil(t, changeFunc)
+	closeFunc(slogt.New(t))
+
+	path := filepath.Join(t.TempDir(), "changes.jsonl")
+	request := migration.Request{ChangesLogPath: path}
+
+	// the changes of each transfer are appended
+	for _, change := range []progress.Change{
+		{Action: progress.ChangeCreated, Itemized: ">f+++++++++", Path: "a"},
+		{Action: progress.ChangeDeleted, Itemized: "*deleting", Path: "b"},
+	} {
+		changeFunc, closeFunc, err = openChangesLog(&request)
+		require.NoError(t, err)
+		require.NoError(t, changeFunc(change))
+		closeFunc(slogt.New(t))
+	}
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, `{"action":"created","itemized":">f+++++++++","path":"a"}`+"\n"+
+		`{"action":"deleted","itemized":"*deleting","path":"b"}`+"\n", string(data))
+}
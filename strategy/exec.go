@@ -0,0 +1,176 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"path"
+	"sync/atomic"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/utkuozdemir/pv-migrate/migration"
+)
+
+// Exec copies the data by running tar in a helper pod on each side
+// and relaying the tar stream between the two exec streams through pv-migrate itself.
+//
+// It needs no services, SSH or port-forwarding, only the permission to exec into pods.
+type Exec struct{}
+
+func (r *Exec) Score(mig *migration.Migration, _ *Facts) Score {
+	if mig.Request.DeleteExtraneousFiles {
+		return Score{Reasons: []string{"cannot delete extraneous files on the destination"}}
+	}
+
+	return Score{Value: execScoreValue, Reasons: []string{
+		"the traffic flows over this machine, needs only the permission to exec into pods",
+	}}
+}
+
+func (r *Exec) Run(ctx context.Context, attempt *migration.Attempt, logger *slog.Logger) error {
+	mig := attempt.Migration
+	if mig.Request.DeleteExtraneousFiles {
+		logger.Debug("exec strategy cannot delete extraneous files on the destination")
+
+		return ErrUnaccepted
+	}
+
+	if len(mig.Request.Includes) > 0 || mig.Request.FilterRules != "" {
+		logger.Debug("exec strategy supports only excludes in the filter rules")
+
+		return ErrUnaccepted
+	}
+
+	if !mig.Request.Preserve.Empty() {
+		logger.Debug("exec strategy cannot preserve the file attributes", "preserve", mig.Request.Preserve.Options())
+
+		return ErrUnaccepted
+	}
+
+	if !mig.Request.LargeFile.Empty() {
+		logger.Debug("exec strategy does not support the large file modes", "modes", mig.Request.LargeFile.Modes())
+
+		return ErrUnaccepted
+	}
+
+	if !mig.Request.Ownership.Empty() {
+		logger.Debug("exec strategy cannot change the owners of the files")
+
+		return ErrUnaccepted
+	}
+
+	if mig.Request.ChangesLogPath != "" {
+		logger.Debug("exec strategy cannot itemize the changes of the files")
+
+		return ErrUnaccepted
+	}
+
+	if mig.Request.AllowVanishedFiles {
+		logger.Debug("exec strategy cannot ignore the vanished files")
+
+		return ErrUnaccepted
+	}
+
+	if mig.Request.Parallel > 1 {
+		logger.Debug("exec strategy cannot transfer in parallel")
+
+		return ErrUnaccepted
+	}
+
+	if mig.Request.BwLimit != "" || mig.Request.Window != nil {
+		logger.Debug("exec strategy cannot limit the bandwidth or pause the transfer outside the window")
+
+		return ErrUnaccepted
+	}
+
+	sourceInfo := mig.SourceInfo
+	destInfo := mig.DestInfo
+
+	srcReleaseName := attempt.HelmReleaseNamePrefix + "-src"
+	destReleaseName := attempt.HelmReleaseNamePrefix + "-dest"
+	releaseNames := []string{srcReleaseName, destReleaseName}
+
+	doneCh := registerCleanupHook(attempt, releaseNames, logger)
+	defer cleanupAndReleaseHook(ctx, attempt, releaseNames, doneCh, logger)
+
+	srcPod, err := installHelperPod(ctx, attempt, sourceInfo, srcReleaseName,
+		mig.Request.SourceMountReadOnly, logger)
+	if err != nil {
+		return fmt.Errorf("failed to install on source: %w", err)
+	}
+
+	destPod, err := installHelperPod(ctx, attempt, destInfo, destReleaseName, false, logger)
+	if err != nil {
+		return fmt.Errorf("failed to install on destination: %w", err)
+	}
+
+	srcCmd, destCmd := buildTarCmdsExec(mig)
+
+	total, err := estimateSize(ctx, sourceInfo, srcPod, path.Join(localDirMountPath, mig.Request.Source.Path),
+		mig.Request.Excludes)
+	if err != nil {
+		logger.Warn("🔶 Failed to estimate the size of the data, progress will not be accurate", "error", err)
+
+		total = -1
+	}
+
+	reader, writer := io.Pipe()
+
+	var relayed byteCounter
+
+	relayReader, finishProgress := newProgressReader(ctx, mig, io.TeeReader(reader, &relayed),
+		total, "📂 Copying data...")
+	defer finishProgress()
+
+	var eg errgroup.Group //nolint:varnamelen
+
+	eg.Go(func() error {
+		err := execInPod(ctx, sourceInfo, srcPod, srcCmd, nil, writer)
+		writer.CloseWithError(err)
+
+		if err != nil {
+			return fmt.Errorf("failed to run tar on source: %w", err)
+		}
+
+		return nil
+	})
+
+	eg.Go(func() error {
+		err := execInPod(ctx, destInfo, destPod, destCmd, relayReader, io.Discard)
+		reader.CloseWithError(err)
+
+		if err != nil {
+			return fmt.Errorf("failed to run tar on destination: %w", err)
+		}
+
+		return nil
+	})
+
+	if err = eg.Wait(); err != nil {
+		return err //nolint:wrapcheck
+	}
+
+	logger.Info("📊 Relayed tar stream", "bytes", relayed.Load())
+
+	return nil
+}
+
+func buildTarCmdsExec(mig *migration.Migration) ([]string, []string) {
+	srcDir := path.Join(localDirMountPath, mig.Request.Source.Path)
+	destDir := path.Join(localDirMountPath, mig.Request.Dest.Path)
+
+	return tarCreateCmd(srcDir, mig.Request.Excludes), tarExtractCmd(destDir, mig.Request.NoChown)
+}
+
+// byteCounter is an io.Writer which counts the bytes written into it.
+type byteCounter struct {
+	atomic.Int64
+}
+
+func (c *byteCounter) Write(p []byte) (int, error) {
+	c.Add(int64(len(p)))
+
+	return len(p), nil
+}
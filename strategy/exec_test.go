@@ -0,0 +1,72 @@
+package strategy
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/utkuozdemir/pv-migrate/migration"
+)
+
+func TestBuildTarCmdsExec(t *testing.T) {
+	t.Parallel()
+
+	mig := migration.Migration{
+		Request: &migration.Request{
+			Source:  &migration.PVCInfo{Path: "/"},
+			Dest:    &migration.PVCInfo{Path: "sub/dir"},
+			NoChown: true,
+		},
+	}
+
+	srcCmd, destCmd := buildTarCmdsExec(&mig)
+	assert.Equal(t, []string{"tar", "-c", "-f", "-", "-C", "/data", "."}, srcCmd)
+	assert.Equal(t, []string{"sh", "-c", `mkdir -p "$0" && tar -x -f - -C "$0" -o`, "/data/sub/dir"}, destCmd)
+}
+
+func TestBuildTarCmdsExecExcludes(t *testing.T) {
+	t.Parallel()
+
+	if _, err := exec.LookPath("tar"); err != nil {
+		t.Skip("tar binary not found")
+	}
+
+	dir := t.TempDir()
+
+	for _, file := range []string{"cache/a", "sub/cache/b", "lost+found/c", "keep/d"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, filepath.Dir(file)), 0o755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, file), nil, 0o600))
+	}
+
+	mig := migration.Migration{
+		Request: &migration.Request{
+			Source:   &migration.PVCInfo{Path: "/"},
+			Dest:     &migration.PVCInfo{Path: "/"},
+			Excludes: []string{"lost+found", "/cache/"},
+		},
+	}
+
+	srcCmd, _ := buildTarCmdsExec(&mig)
+	srcCmd[len(srcCmd)-2] = dir
+
+	stream, err := exec.Command(srcCmd[0], srcCmd[1:]...).Output()
+	require.NoError(t, err)
+
+	list := exec.Command("tar", "-t", "-f", "-")
+	list.Stdin = bytes.NewReader(stream)
+
+	members, err := list.Output()
+	require.NoError(t, err)
+
+	files := strings.Fields(string(members))
+	assert.Contains(t, files, "./sub/cache/b", "only the top-level cache is excluded")
+	assert.Contains(t, files, "./keep/d")
+	assert.NotContains(t, files, "./cache/a")
+	assert.NotContains(t, files, "./lost+found/c")
+}
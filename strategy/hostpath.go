@@ -0,0 +1,277 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/utkuozdemir/pv-migrate/migration"
+	"github.com/utkuozdemir/pv-migrate/pvc"
+	"github.com/utkuozdemir/pv-migrate/ssh"
+	"github.com/utkuozdemir/pv-migrate/transfer"
+)
+
+// HostPath reads the data of a node-local source volume (a hostPath or local PV, e.g. by local-path-provisioner)
+// directly from its directory on the node, without mounting the PVC.
+//
+// If the destination is a node-local volume on the same node, the data is copied within a single pod.
+// Otherwise, the data is pushed over SSH into an sshd pod which mounts the destination PVC.
+type HostPath struct{}
+
+// nodeLocalVolume is the directory of a hostPath or local PV on the node it is pinned to.
+type nodeLocalVolume struct {
+	path         string
+	nodeSelector *corev1.NodeSelector
+}
+
+func (r *HostPath) Score(mig *migration.Migration, facts *Facts) Score {
+	if !facts.SourceNodeLocal {
+		return Score{Reasons: []string{"source PV is not a hostPath or local volume pinned to a node"}}
+	}
+
+	if !sameCluster(mig) {
+		return Score{Reasons: []string{"PVCs are in different clusters"}}
+	}
+
+	if mig.Request.Rootless {
+		return Score{Reasons: []string{"hostPath volumes are forbidden by the restricted Pod Security Standard"}}
+	}
+
+	return Score{Value: hostPathScoreValue, Reasons: []string{
+		"source PV is node-local, its directory can be read on the node without mounting the PVC",
+	}}
+}
+
+func (r *HostPath) Run(ctx context.Context, attempt *migration.Attempt, logger *slog.Logger) error {
+	mig := attempt.Migration
+	if !sameCluster(mig) {
+		return ErrUnaccepted
+	}
+
+	if mig.Request.Rootless {
+		logger.Debug("hostpath strategy cannot mount the node directories as a non-root user")
+
+		return ErrUnaccepted
+	}
+
+	srcVolume, err := getNodeLocalVolume(ctx, mig.SourceInfo)
+	if err != nil {
+		return err
+	}
+
+	if srcVolume == nil {
+		return ErrUnaccepted
+	}
+
+	destVolume, err := getNodeLocalVolume(ctx, mig.DestInfo)
+	if err != nil {
+		return err
+	}
+
+	srcAffinity, err := buildNodeLocalAffinityHelmValues(srcVolume)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("📁 Reading the source volume from its node", "path", srcVolume.path)
+
+	if destVolume != nil && equality.Semantic.DeepEqual(srcVolume.nodeSelector, destVolume.nodeSelector) {
+		return r.runOnNode(ctx, attempt, srcVolume, destVolume, srcAffinity, logger)
+	}
+
+	return r.runWithSshd(ctx, attempt, srcVolume, srcAffinity, logger)
+}
+
+// runOnNode copies the data between the directories of the source and destination volumes on their node.
+func (r *HostPath) runOnNode(ctx context.Context, attempt *migration.Attempt,
+	srcVolume, destVolume *nodeLocalVolume, affinity map[string]any, logger *slog.Logger,
+) error {
+	mig := attempt.Migration
+	destInfo := mig.DestInfo
+	namespace := destInfo.Claim.Namespace
+
+	releaseName := attempt.HelmReleaseNamePrefix
+	releaseNames := []string{releaseName}
+
+	doneCh := registerCleanupHook(attempt, releaseNames, logger)
+	defer cleanupAndReleaseHook(ctx, attempt, releaseNames, doneCh, logger)
+
+	src := transfer.Endpoint{Path: srcMountPath + "/" + mig.Request.Source.Path}
+	dest := transfer.Endpoint{Path: destMountPath + "/" + mig.Request.Dest.Path}
+
+	transferCmd, err := buildTransferCmd(mig, &src, &dest, "")
+	if err != nil {
+		return err
+	}
+
+	vals := map[string]any{
+		"rsync": map[string]any{
+			"enabled":   true,
+			"namespace": namespace,
+			"hostPathMounts": []map[string]any{
+				{
+					"hostPath":  srcVolume.path,
+					"readOnly":  mig.Request.SourceMountReadOnly,
+					"mountPath": srcMountPath,
+				},
+				{
+					"hostPath":  destVolume.path,
+					"mountPath": destMountPath,
+				},
+			},
+			"command":              transferCmd,
+			"suspend":              transferJobSuspended(mig.Request),
+			"filterRules":          mig.Request.FilterRules,
+			"filterRulesMountPath": filterRulesMountPath,
+			"affinity":             affinity,
+		},
+	}
+
+	if err = installHelmChart(ctx, attempt, destInfo, releaseName, vals, logger); err != nil {
+		return fmt.Errorf("failed to install helm chart: %w", err)
+	}
+
+	return waitForTransferJob(ctx, mig, destInfo.ClusterClient.KubeClient, namespace, releaseName+"-rsync", logger)
+}
+
+// runWithSshd pushes the data from the directory of the source volume into an sshd which mounts the destination PVC.
+func (r *HostPath) runWithSshd(ctx context.Context, attempt *migration.Attempt,
+	srcVolume *nodeLocalVolume, affinity map[string]any, logger *slog.Logger,
+) error {
+	mig := attempt.Migration
+	sourceInfo := mig.SourceInfo
+	destInfo := mig.DestInfo
+	sourceNs := sourceInfo.Claim.Namespace
+	keyAlgorithm := mig.Request.KeyAlgorithm
+
+	logger.Info("🔑 Generating SSH key pair", "algorithm", keyAlgorithm)
+
+	publicKey, privateKey, err := ssh.CreateSSHKeyPair(keyAlgorithm)
+	if err != nil {
+		return fmt.Errorf("failed to create ssh key pair: %w", err)
+	}
+
+	privateKeyMountPath := "/tmp/id_" + keyAlgorithm
+
+	srcReleaseName := attempt.HelmReleaseNamePrefix + "-src"
+	destReleaseName := attempt.HelmReleaseNamePrefix + "-dest"
+	releaseNames := []string{srcReleaseName, destReleaseName}
+
+	doneCh := registerCleanupHook(attempt, releaseNames, logger)
+	defer cleanupAndReleaseHook(ctx, attempt, releaseNames, doneCh, logger)
+
+	destVals := map[string]any{
+		"sshd": map[string]any{
+			"enabled":   true,
+			"namespace": destInfo.Claim.Namespace,
+			"publicKey": publicKey,
+			"pvcMounts": []map[string]any{
+				{
+					"name":      destInfo.Claim.Name,
+					"mountPath": destMountPath,
+				},
+			},
+			"affinity": destInfo.AffinityHelmValues,
+		},
+	}
+
+	if err = installHelmChart(ctx, attempt, destInfo, destReleaseName, destVals, logger); err != nil {
+		return fmt.Errorf("failed to install on dest: %w", err)
+	}
+
+	sshTargetHost := destReleaseName + "-sshd." + destInfo.Claim.Namespace
+	if mig.Request.DestHostOverride != "" {
+		sshTargetHost = mig.Request.DestHostOverride
+	}
+
+	src := transfer.Endpoint{Path: srcMountPath + "/" + mig.Request.Source.Path}
+	dest := transfer.Endpoint{Path: destMountPath + "/" + mig.Request.Dest.Path, SSHHost: sshTargetHost}
+
+	transferCmd, err := buildTransferCmd(mig, &src, &dest, privateKeyMountPath)
+	if err != nil {
+		return err
+	}
+
+	rsyncVals := map[string]any{
+		"enabled":             true,
+		"namespace":           sourceNs,
+		"privateKeyMount":     true,
+		"privateKey":          privateKey,
+		"privateKeyMountPath": privateKeyMountPath,
+		"hostPathMounts": []map[string]any{
+			{
+				"hostPath":  srcVolume.path,
+				"readOnly":  mig.Request.SourceMountReadOnly,
+				"mountPath": srcMountPath,
+			},
+		},
+		"command":              transferCmd,
+		"suspend":              transferJobSuspended(mig.Request),
+		"filterRules":          mig.Request.FilterRules,
+		"filterRulesMountPath": filterRulesMountPath,
+		"affinity":             affinity,
+	}
+
+	if err = setSSHClientHelmValues(rsyncVals, mig.Request, privateKeyMountPath); err != nil {
+		return err
+	}
+
+	srcVals := map[string]any{"rsync": rsyncVals}
+
+	if err = installHelmChart(ctx, attempt, sourceInfo, srcReleaseName, srcVals, logger); err != nil {
+		return fmt.Errorf("failed to install on source: %w", err)
+	}
+
+	return waitForTransferJob(ctx, mig, sourceInfo.ClusterClient.KubeClient, sourceNs, srcReleaseName+"-rsync", logger)
+}
+
+// getNodeLocalVolume returns the directory and the node affinity of the PV bound to the PVC,
+// or nil if it is not a hostPath or local volume pinned to a node.
+func getNodeLocalVolume(ctx context.Context, info *pvc.Info) (*nodeLocalVolume, error) {
+	volumeName := info.Claim.Spec.VolumeName
+	if volumeName == "" {
+		return nil, nil //nolint:nilnil
+	}
+
+	pv, err := info.ClusterClient.KubeClient.CoreV1().PersistentVolumes().Get(ctx, volumeName, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get PV %s: %w", volumeName, err)
+	}
+
+	var path string
+
+	switch {
+	case pv.Spec.HostPath != nil:
+		path = pv.Spec.HostPath.Path
+	case pv.Spec.Local != nil:
+		path = pv.Spec.Local.Path
+	default:
+		return nil, nil //nolint:nilnil
+	}
+
+	if pv.Spec.NodeAffinity == nil || pv.Spec.NodeAffinity.Required == nil {
+		return nil, nil //nolint:nilnil
+	}
+
+	return &nodeLocalVolume{path: path, nodeSelector: pv.Spec.NodeAffinity.Required}, nil
+}
+
+func buildNodeLocalAffinityHelmValues(volume *nodeLocalVolume) (map[string]any, error) {
+	affinity := corev1.Affinity{
+		NodeAffinity: &corev1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: volume.nodeSelector,
+		},
+	}
+
+	vals, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&affinity)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert node affinity to helm values: %w", err)
+	}
+
+	return vals, nil
+}
@@ -0,0 +1,180 @@
+package strategy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/neilotoole/slogt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/utkuozdemir/pv-migrate/migration"
+	"github.com/utkuozdemir/pv-migrate/pvc"
+)
+
+func TestGetNodeLocalVolume(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	nodeSelector := buildTestHostnameSelector("node1")
+
+	hostPathPV := corev1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: "pv-hostpath"},
+		Spec: corev1.PersistentVolumeSpec{
+			PersistentVolumeSource: corev1.PersistentVolumeSource{
+				HostPath: &corev1.HostPathVolumeSource{Path: "/opt/local-path-provisioner/pvc-1"},
+			},
+			NodeAffinity: &corev1.VolumeNodeAffinity{Required: nodeSelector},
+		},
+	}
+
+	localPV := corev1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: "pv-local"},
+		Spec: corev1.PersistentVolumeSpec{
+			PersistentVolumeSource: corev1.PersistentVolumeSource{
+				Local: &corev1.LocalVolumeSource{Path: "/mnt/disks/ssd1"},
+			},
+			NodeAffinity: &corev1.VolumeNodeAffinity{Required: nodeSelector},
+		},
+	}
+
+	unpinnedPV := corev1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: "pv-unpinned"},
+		Spec: corev1.PersistentVolumeSpec{
+			PersistentVolumeSource: corev1.PersistentVolumeSource{
+				HostPath: &corev1.HostPathVolumeSource{Path: "/tmp/data"},
+			},
+		},
+	}
+
+	nfsPV := corev1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: "pv-nfs"},
+		Spec: corev1.PersistentVolumeSpec{
+			PersistentVolumeSource: corev1.PersistentVolumeSource{
+				NFS: &corev1.NFSVolumeSource{Server: "nfs", Path: "/exports"},
+			},
+		},
+	}
+
+	cli := buildTestClient(&hostPathPV, &localPV, &unpinnedPV, &nfsPV,
+		buildTestBoundPVC("ns", "hostpath", "pv-hostpath"),
+		buildTestBoundPVC("ns", "local", "pv-local"),
+		buildTestBoundPVC("ns", "unpinned", "pv-unpinned"),
+		buildTestBoundPVC("ns", "nfs", "pv-nfs"),
+		buildTestBoundPVC("ns", "unbound", ""),
+	)
+
+	tests := map[string]*nodeLocalVolume{
+		"hostpath": {path: "/opt/local-path-provisioner/pvc-1", nodeSelector: nodeSelector},
+		"local":    {path: "/mnt/disks/ssd1", nodeSelector: nodeSelector},
+		"unpinned": nil,
+		"nfs":      nil,
+		"unbound":  nil,
+	}
+
+	for name, expected := range tests {
+		info, err := pvc.New(ctx, cli, "ns", name)
+		require.NoError(t, err)
+
+		volume, err := getNodeLocalVolume(ctx, info)
+		require.NoError(t, err)
+		assert.Equal(t, expected, volume, name)
+	}
+}
+
+func TestHostPathScore(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	pv := corev1.PersistentVolume{
+		ObjectMeta: metav1.ObjectMeta{Name: "pv1"},
+		Spec: corev1.PersistentVolumeSpec{
+			PersistentVolumeSource: corev1.PersistentVolumeSource{
+				Local: &corev1.LocalVolumeSource{Path: "/mnt/disks/ssd1"},
+			},
+			NodeAffinity: &corev1.VolumeNodeAffinity{Required: buildTestHostnameSelector("node1")},
+		},
+	}
+
+	cli := buildTestClient(&pv, buildTestBoundPVC("ns1", "pvc1", "pv1"), buildTestPVC("ns2", "pvc2"))
+
+	src, err := pvc.New(ctx, cli, "ns1", "pvc1")
+	require.NoError(t, err)
+
+	dst, err := pvc.New(ctx, cli, "ns2", "pvc2")
+	require.NoError(t, err)
+
+	mig := migration.Migration{Request: &migration.Request{}, SourceInfo: src, DestInfo: dst}
+
+	facts := GatherFacts(ctx, &mig, slogt.New(t))
+	assert.True(t, facts.SourceNodeLocal)
+	assert.Equal(t, hostPathScoreValue, (&HostPath{}).Score(&mig, facts).Value)
+
+	mig.Request.Rootless = true
+	assert.Equal(t, 0, (&HostPath{}).Score(&mig, facts).Value)
+
+	mig.Request.Rootless = false
+
+	mig.DestInfo = &pvc.Info{
+		ClusterClient: buildTestClientWithAPIServerHost("https://127.0.0.2:6443"),
+		Claim:         dst.Claim,
+	}
+	assert.Equal(t, 0, (&HostPath{}).Score(&mig, facts).Value)
+}
+
+func TestBuildNodeLocalAffinityHelmValues(t *testing.T) {
+	t.Parallel()
+
+	vals, err := buildNodeLocalAffinityHelmValues(&nodeLocalVolume{
+		path:         "/data",
+		nodeSelector: buildTestHostnameSelector("node1"),
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]any{
+		"nodeAffinity": map[string]any{
+			"requiredDuringSchedulingIgnoredDuringExecution": map[string]any{
+				"nodeSelectorTerms": []any{
+					map[string]any{
+						"matchExpressions": []any{
+							map[string]any{
+								"key":      "kubernetes.io/hostname",
+								"operator": "In",
+								"values":   []any{"node1"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}, vals)
+}
+
+func buildTestHostnameSelector(node string) *corev1.NodeSelector {
+	return &corev1.NodeSelector{
+		NodeSelectorTerms: []corev1.NodeSelectorTerm{
+			{
+				MatchExpressions: []corev1.NodeSelectorRequirement{
+					{
+						Key:      "kubernetes.io/hostname",
+						Operator: corev1.NodeSelectorOpIn,
+						Values:   []string{node},
+					},
+				},
+			},
+		},
+	}
+}
+
+func buildTestBoundPVC(namespace, name, volumeName string) *corev1.PersistentVolumeClaim {
+	claim := buildTestPVC(namespace, name, corev1.ReadWriteOnce)
+	claim.Spec.VolumeName = volumeName
+
+	return claim
+}
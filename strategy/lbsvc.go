@@ -7,13 +7,42 @@ import (
 
 	"github.com/utkuozdemir/pv-migrate/k8s"
 	"github.com/utkuozdemir/pv-migrate/migration"
-	"github.com/utkuozdemir/pv-migrate/rsync"
 	"github.com/utkuozdemir/pv-migrate/ssh"
+	"github.com/utkuozdemir/pv-migrate/transfer"
 	"github.com/utkuozdemir/pv-migrate/util"
 )
 
 type LbSvc struct{}
 
+func (r *LbSvc) Score(mig *migration.Migration, facts *Facts) Score {
+	var score Score
+
+	switch {
+	case facts.LoadBalancerAvailable == nil:
+		score = Score{Value: lbSvcUnknownScoreValue, Reasons: []string{
+			"could not determine if a load balancer is available in the source cluster",
+		}}
+	case *facts.LoadBalancerAvailable:
+		score = Score{Value: lbSvcScoreValue, Reasons: []string{
+			"a LoadBalancer service with an address exists in the source cluster",
+		}}
+	default:
+		score = Score{Value: lbSvcUnavailableScoreValue, Reasons: []string{
+			"no LoadBalancer service with an address exists in the source cluster, " +
+				"a load balancer might not be available",
+		}}
+	}
+
+	if sameCluster(mig) {
+		penalize(&score, sameClusterLoadBalancerPenalty,
+			"PVCs are in the same cluster, the traffic would leave the cluster needlessly")
+	}
+
+	penalizeNetworkPolicies(&score, facts)
+
+	return score
+}
+
 func (r *LbSvc) Run(ctx context.Context, attempt *migration.Attempt, logger *slog.Logger) error {
 	mig := attempt.Migration
 
@@ -34,12 +63,13 @@ func (r *LbSvc) Run(ctx context.Context, attempt *migration.Attempt, logger *slo
 
 	srcReleaseName := attempt.HelmReleaseNamePrefix + "-src"
 	destReleaseName := attempt.HelmReleaseNamePrefix + "-dest"
-	releaseNames := []string{srcReleaseName, destReleaseName}
+	probeReleaseName := attempt.HelmReleaseNamePrefix + "-probe"
+	releaseNames := []string{srcReleaseName, probeReleaseName, destReleaseName}
 
 	doneCh := registerCleanupHook(attempt, releaseNames, logger)
 	defer cleanupAndReleaseHook(ctx, attempt, releaseNames, doneCh, logger)
 
-	err = installOnSource(attempt, srcReleaseName, publicKey, srcMountPath, logger)
+	err = installOnSource(ctx, attempt, srcReleaseName, publicKey, srcMountPath, "LoadBalancer", logger)
 	if err != nil {
 		return fmt.Errorf("failed to install on source: %w", err)
 	}
@@ -57,25 +87,24 @@ func (r *LbSvc) Run(ctx context.Context, attempt *migration.Attempt, logger *slo
 		sshTargetHost = mig.Request.DestHostOverride
 	}
 
-	err = installOnDest(attempt, destReleaseName, privateKey, privateKeyMountPath,
-		sshTargetHost, srcMountPath, destMountPath, logger)
+	if err = probeSSHD(ctx, attempt, probeReleaseName, sshTargetHost, 0, logger); err != nil {
+		return err
+	}
+
+	err = installOnDest(ctx, attempt, destReleaseName, privateKey, privateKeyMountPath,
+		sshTargetHost, 0, srcMountPath, destMountPath, logger)
 	if err != nil {
 		return fmt.Errorf("failed to install on dest: %w", err)
 	}
 
-	showProgressBar := !attempt.Migration.Request.NoProgressBar
 	kubeClient := destInfo.ClusterClient.KubeClient
 	jobName := destReleaseName + "-rsync"
 
-	if err = k8s.WaitForJobCompletion(ctx, kubeClient, destNs, jobName, showProgressBar, logger); err != nil {
-		return fmt.Errorf("failed to wait for job completion: %w", err)
-	}
-
-	return nil
+	return waitForTransferJob(ctx, mig, kubeClient, destNs, jobName, logger)
 }
 
-func installOnSource(attempt *migration.Attempt, releaseName,
-	publicKey, srcMountPath string, logger *slog.Logger,
+func installOnSource(ctx context.Context, attempt *migration.Attempt, releaseName,
+	publicKey, srcMountPath, serviceType string, logger *slog.Logger,
 ) error {
 	mig := attempt.Migration
 	sourceInfo := mig.SourceInfo
@@ -87,7 +116,7 @@ func installOnSource(attempt *migration.Attempt, releaseName,
 			"namespace": namespace,
 			"publicKey": publicKey,
 			"service": map[string]any{
-				"type": "LoadBalancer",
+				"type": serviceType,
 			},
 			"pvcMounts": []map[string]any{
 				{
@@ -100,53 +129,51 @@ func installOnSource(attempt *migration.Attempt, releaseName,
 		},
 	}
 
-	return installHelmChart(attempt, sourceInfo, releaseName, vals, logger)
+	return installHelmChart(ctx, attempt, sourceInfo, releaseName, vals, logger)
 }
 
-func installOnDest(attempt *migration.Attempt, releaseName, privateKey,
-	privateKeyMountPath, sshHost, srcMountPath, destMountPath string, logger *slog.Logger,
+func installOnDest(ctx context.Context, attempt *migration.Attempt, releaseName, privateKey,
+	privateKeyMountPath, sshHost string, sshPort int, srcMountPath, destMountPath string, logger *slog.Logger,
 ) error {
 	mig := attempt.Migration
 	destInfo := mig.DestInfo
 	namespace := destInfo.Claim.Namespace
 
-	srcPath := srcMountPath + "/" + mig.Request.Source.Path
-	destPath := destMountPath + "/" + mig.Request.Dest.Path
-	rsyncCmd := rsync.Cmd{
-		NoChown:    mig.Request.NoChown,
-		Delete:     mig.Request.DeleteExtraneousFiles,
-		SrcPath:    srcPath,
-		DestPath:   destPath,
-		SrcUseSSH:  true,
-		SrcSSHHost: sshHost,
-		Compress:   mig.Request.Compress,
-	}
+	src := transfer.Endpoint{Path: srcMountPath + "/" + mig.Request.Source.Path, SSHHost: sshHost, SSHPort: sshPort}
+	dest := transfer.Endpoint{Path: destMountPath + "/" + mig.Request.Dest.Path}
 
-	rsyncCmdStr, err := rsyncCmd.Build()
+	transferCmd, err := buildTransferCmd(mig, &src, &dest, privateKeyMountPath)
 	if err != nil {
-		return fmt.Errorf("failed to build rsync command: %w", err)
+		return err
 	}
 
-	vals := map[string]any{
-		"rsync": map[string]any{
-			"enabled":             true,
-			"namespace":           namespace,
-			"privateKeyMount":     true,
-			"privateKey":          privateKey,
-			"privateKeyMountPath": privateKeyMountPath,
-			"sshRemoteHost":       sshHost,
-			"pvcMounts": []map[string]any{
-				{
-					"name":      destInfo.Claim.Name,
-					"mountPath": destMountPath,
-				},
+	rsyncVals := map[string]any{
+		"enabled":             true,
+		"namespace":           namespace,
+		"privateKeyMount":     true,
+		"privateKey":          privateKey,
+		"privateKeyMountPath": privateKeyMountPath,
+		"sshRemoteHost":       sshHost,
+		"pvcMounts": []map[string]any{
+			{
+				"name":      destInfo.Claim.Name,
+				"mountPath": destMountPath,
 			},
-			"command":  rsyncCmdStr,
-			"affinity": destInfo.AffinityHelmValues,
 		},
+		"command":              transferCmd,
+		"suspend":              transferJobSuspended(mig.Request),
+		"filterRules":          mig.Request.FilterRules,
+		"filterRulesMountPath": filterRulesMountPath,
+		"affinity":             destInfo.AffinityHelmValues,
+	}
+
+	if err = setSSHClientHelmValues(rsyncVals, mig.Request, privateKeyMountPath); err != nil {
+		return err
 	}
 
-	return installHelmChart(attempt, destInfo, releaseName, vals, logger)
+	vals := map[string]any{"rsync": rsyncVals}
+
+	return installHelmChart(ctx, attempt, destInfo, releaseName, vals, logger)
 }
 
 func formatSSHTargetHost(host string) string {
@@ -18,9 +18,9 @@ import (
 	"github.com/utkuozdemir/pv-migrate/k8s"
 	"github.com/utkuozdemir/pv-migrate/migration"
 	"github.com/utkuozdemir/pv-migrate/pvc"
-	"github.com/utkuozdemir/pv-migrate/rsync"
 	"github.com/utkuozdemir/pv-migrate/rsync/progress"
 	"github.com/utkuozdemir/pv-migrate/ssh"
+	"github.com/utkuozdemir/pv-migrate/transfer"
 )
 
 const (
@@ -33,17 +33,30 @@ const (
 
 type Local struct{}
 
+func (r *Local) Score(*migration.Migration, *Facts) Score {
+	return Score{Value: localScoreValue, Reasons: []string{
+		"the traffic flows over this machine, slow but only needs the API servers to be reachable",
+	}}
+}
+
 func (r *Local) Run(ctx context.Context, attempt *migration.Attempt, logger *slog.Logger) error {
-	_, err := exec.LookPath("ssh")
-	if err != nil {
-		return errors.New("ssh binary not found")
+	mig := attempt.Migration
+	if mig.Request.FilterRules != "" {
+		logger.Debug("local strategy cannot mount the filter rules into the sshd")
+
+		return ErrUnaccepted
+	}
+
+	if mig.Request.Window != nil {
+		logger.Debug("local strategy cannot pause the transfer outside the window")
+
+		return ErrUnaccepted
 	}
 
-	mig := attempt.Migration
 	sourceInfo := mig.SourceInfo
 	destInfo := mig.DestInfo
 
-	srcReleaseName, destReleaseName, privateKey, err := r.installLocalReleases(attempt, logger)
+	srcReleaseName, destReleaseName, privateKey, err := r.installLocalReleases(ctx, attempt, logger)
 	if err != nil {
 		return fmt.Errorf("failed to install local releases: %w", err)
 	}
@@ -53,57 +66,86 @@ func (r *Local) Run(ctx context.Context, attempt *migration.Attempt, logger *slo
 	doneCh := registerCleanupHook(attempt, releaseNames, logger)
 	defer cleanupAndReleaseHook(ctx, attempt, releaseNames, doneCh, logger)
 
-	srcFwdPort, srcStopChan, err := portForwardToSshd(ctx, sourceInfo, srcReleaseName, logger)
+	srcFwdPort, srcStopChan, err := portForwardToSshd(ctx, mig.Request, sourceInfo, srcReleaseName, logger)
 	if err != nil {
 		return fmt.Errorf("failed to port-forward to source: %w", err)
 	}
 
 	defer func() { srcStopChan <- struct{}{} }()
 
-	destFwdPort, destStopChan, err := portForwardToSshd(ctx, destInfo, destReleaseName, logger)
+	destFwdPort, destStopChan, err := portForwardToSshd(ctx, mig.Request, destInfo, destReleaseName, logger)
 	if err != nil {
 		return fmt.Errorf("failed to port-forward to destination: %w", err)
 	}
 
 	defer func() { destStopChan <- struct{}{} }()
 
-	privateKeyFile, err := writePrivateKeyToTempFile(privateKey)
+	transferCmd, err := buildTransferCmdLocal(mig)
 	if err != nil {
-		return fmt.Errorf("failed to write private key to temp file: %w", err)
+		return err
 	}
 
-	defer func() {
-		os.Remove(privateKeyFile)
-	}()
-
-	rsyncCmd, err := buildRsyncCmdLocal(mig)
-	if err != nil {
-		return fmt.Errorf("failed to build rsync command: %w", err)
+	run := func(output io.Writer) error {
+		return ssh.RunWithReverseTunnel(ctx, &ssh.ReverseTunnelRequest{
+			Addr:       net.JoinHostPort("localhost", strconv.Itoa(srcFwdPort)),
+			User:       sshUser(mig.Request),
+			PrivateKey: privateKey,
+			RemotePort: sshReverseTunnelPort,
+			TargetAddr: net.JoinHostPort("localhost", strconv.Itoa(destFwdPort)),
+			Command:    transferCmd,
+			Stdout:     output,
+			Stderr:     output,
+		}, logger)
 	}
 
-	cmd := exec.Command("ssh", "-i", privateKeyFile,
-		"-p", strconv.Itoa(srcFwdPort),
-		"-R", fmt.Sprintf("%d:localhost:%d", sshReverseTunnelPort, destFwdPort),
-		"-o", "StrictHostKeyChecking=no", "-o", "UserKnownHostsFile=/dev/null", "root@localhost",
-		rsyncCmd,
-	)
+	if err = runWithProgressLocal(ctx, attempt, run, logger); err != nil {
+		var cmdErr *ssh.CommandError
+		if errors.As(err, &cmdErr) {
+			if engine, engineErr := transfer.Get(mig.Request.Engine); engineErr == nil {
+				status := engine.ClassifyExitCode(cmdErr.ExitStatus, cmdErr.Stderr)
+
+				logger.Warn("🔶 Transfer failed on the source", "exit_status", cmdErr.ExitStatus,
+					"reason", status.Reason, "hint", status.Hint, "signal", cmdErr.Signal, "stderr", cmdErr.Stderr)
+			}
+		}
 
-	if err = runCmdLocal(ctx, attempt, cmd, logger); err != nil {
-		return fmt.Errorf("failed to run rsync command: %w", err)
+		return fmt.Errorf("failed to run transfer command: %w", err)
 	}
 
 	return nil
 }
 
-func runCmdLocal(ctx context.Context, attempt *migration.Attempt, cmd *exec.Cmd, logger *slog.Logger) (retErr error) {
+func runCmdLocal(ctx context.Context, attempt *migration.Attempt, cmd *exec.Cmd, logger *slog.Logger) error {
+	return runWithProgressLocal(ctx, attempt, func(output io.Writer) error {
+		cmd.Stdout = output
+		cmd.Stderr = output
+
+		return cmd.Run() //nolint:wrapcheck
+	}, logger)
+}
+
+// runWithProgressLocal calls the run function, which runs the transfer command and writes its output
+// into the given writer, and displays the progress of the transfer based on that output.
+func runWithProgressLocal(ctx context.Context, attempt *migration.Attempt,
+	run func(output io.Writer) error, logger *slog.Logger,
+) (retErr error) {
+	engine, err := transfer.Get(attempt.Migration.Request.Engine)
+	if err != nil {
+		return fmt.Errorf("failed to get transfer engine: %w", err)
+	}
+
+	changeFunc, closeChangesLog, err := openChangesLog(attempt.Migration.Request)
+	if err != nil {
+		return err
+	}
+
+	defer closeChangesLog(logger)
+
 	reader, writer := io.Pipe()
-	cmd.Stdout = writer
-	cmd.Stderr = writer
 
 	errorCh := make(chan error)
 
-	//nolint:godox
-	go func() { errorCh <- cmd.Run() }() // todo: this is a mess, refactor
+	go func() { errorCh <- run(writer) }()
 
 	canDisplayProgressBar := ctx.Value(progress.CanDisplayProgressBarContextKey{}) != nil
 	progressBarRequested := !attempt.Migration.Request.NoProgressBar
@@ -111,6 +153,8 @@ func runCmdLocal(ctx context.Context, attempt *migration.Attempt, cmd *exec.Cmd,
 
 	progressLogger := progress.NewLogger(progress.LoggerOptions{
 		ShowProgressBar: showProgressBar,
+		ParseLineFunc:   transfer.ParseProgressFunc(engine, attempt.Migration.Request.Parallel),
+		ChangeFunc:      changeFunc,
 		LogStreamFunc: func(context.Context) (io.ReadCloser, error) {
 			return reader, nil
 		},
@@ -127,6 +171,10 @@ func runCmdLocal(ctx context.Context, attempt *migration.Attempt, cmd *exec.Cmd,
 
 	defer func() {
 		retErr = errors.Join(retErr, eg.Wait())
+
+		if stats, ok := progressLogger.Stats(); ok && retErr == nil {
+			logger.Info("📊 Transfer summary", "stats", stats)
+		}
 	}()
 
 	select {
@@ -143,30 +191,27 @@ func runCmdLocal(ctx context.Context, attempt *migration.Attempt, cmd *exec.Cmd,
 	}
 }
 
-func buildRsyncCmdLocal(mig *migration.Migration) (string, error) {
-	srcPath := srcMountPath + "/" + mig.Request.Source.Path
-	destPath := destMountPath + "/" + mig.Request.Dest.Path
-
-	rsyncCmd := rsync.Cmd{
-		Port:        sshReverseTunnelPort,
-		NoChown:     mig.Request.NoChown,
-		Delete:      mig.Request.DeleteExtraneousFiles,
-		SrcPath:     srcPath,
-		DestPath:    destPath,
-		DestUseSSH:  true,
-		DestSSHHost: "localhost",
-		Compress:    mig.Request.Compress,
+func buildTransferCmdLocal(mig *migration.Migration) (string, error) {
+	src := transfer.Endpoint{Path: srcMountPath + "/" + mig.Request.Source.Path}
+	dest := transfer.Endpoint{
+		Path:    destMountPath + "/" + mig.Request.Dest.Path,
+		SSHHost: "localhost",
+		SSHPort: sshReverseTunnelPort,
 	}
 
-	cmd, err := rsyncCmd.Build()
-	if err != nil {
-		return "", fmt.Errorf("failed to build rsync command: %w", err)
-	}
+	// the destination is reached over the reverse tunnel on localhost, so the jump hosts do not apply
+	request := *mig.Request
+	request.SSHOptions.ProxyJump = nil
+
+	localMig := *mig
+	localMig.Request = &request
 
-	return cmd, nil
+	return buildTransferCmd(&localMig, &src, &dest, "/tmp/id_"+mig.Request.KeyAlgorithm)
 }
 
-func (r *Local) installLocalReleases(attempt *migration.Attempt, logger *slog.Logger) (string, string, string, error) {
+func (r *Local) installLocalReleases(ctx context.Context, attempt *migration.Attempt,
+	logger *slog.Logger,
+) (string, string, string, error) {
 	keyAlgorithm := attempt.Migration.Request.KeyAlgorithm
 
 	logger.Info("🔑 Generating SSH key pair", "algorithm", keyAlgorithm)
@@ -181,13 +226,13 @@ func (r *Local) installLocalReleases(attempt *migration.Attempt, logger *slog.Lo
 	srcReleaseName := attempt.HelmReleaseNamePrefix + "-src"
 	destReleaseName := attempt.HelmReleaseNamePrefix + "-dest"
 
-	err = installLocalOnSource(attempt, srcReleaseName, publicKey,
+	err = installLocalOnSource(ctx, attempt, srcReleaseName, publicKey,
 		privateKey, privateKeyMountPath, srcMountPath, logger)
 	if err != nil {
 		return "", "", "", err
 	}
 
-	err = installLocalOnDest(attempt, destReleaseName, publicKey, destMountPath, logger)
+	err = installLocalOnDest(ctx, attempt, destReleaseName, publicKey, destMountPath, logger)
 	if err != nil {
 		return "", "", "", err
 	}
@@ -206,7 +251,7 @@ func getSshdPodForHelmRelease(ctx context.Context, pvcInfo *pvc.Info, name strin
 	return pod, nil
 }
 
-func installLocalOnSource(attempt *migration.Attempt, releaseName,
+func installLocalOnSource(ctx context.Context, attempt *migration.Attempt, releaseName,
 	publicKey, privateKey, privateKeyMountPath, srcMountPath string, logger *slog.Logger,
 ) error {
 	mig := attempt.Migration
@@ -232,10 +277,10 @@ func installLocalOnSource(attempt *migration.Attempt, releaseName,
 		},
 	}
 
-	return installHelmChart(attempt, sourceInfo, releaseName, vals, logger)
+	return installHelmChart(ctx, attempt, sourceInfo, releaseName, vals, logger)
 }
 
-func installLocalOnDest(attempt *migration.Attempt, releaseName,
+func installLocalOnDest(ctx context.Context, attempt *migration.Attempt, releaseName,
 	publicKey, destMountPath string, logger *slog.Logger,
 ) error {
 	mig := attempt.Migration
@@ -264,7 +309,7 @@ func installLocalOnDest(attempt *migration.Attempt, releaseName,
 
 	defer func() { _ = os.Remove(valsFile) }()
 
-	return installHelmChart(attempt, destInfo, releaseName, vals, logger)
+	return installHelmChart(ctx, attempt, destInfo, releaseName, vals, logger)
 }
 
 func writePrivateKeyToTempFile(privateKey string) (string, error) {
@@ -290,7 +335,7 @@ func writePrivateKeyToTempFile(privateKey string) (string, error) {
 	return name, nil
 }
 
-func portForwardToSshd(ctx context.Context, pvcInfo *pvc.Info,
+func portForwardToSshd(ctx context.Context, request *migration.Request, pvcInfo *pvc.Info,
 	helmReleaseName string, logger *slog.Logger,
 ) (int, chan<- struct{}, error) {
 	sshdPod, err := getSshdPodForHelmRelease(ctx, pvcInfo, helmReleaseName)
@@ -316,7 +361,7 @@ func portForwardToSshd(ctx context.Context, pvcInfo *pvc.Info,
 			PodNs:      namespace,
 			PodName:    name,
 			LocalPort:  port,
-			PodPort:    localSSHPort,
+			PodPort:    sshdPodPort(request),
 			StopCh:     stopChan,
 			ReadyCh:    readyChan,
 		}, logger)
@@ -0,0 +1,153 @@
+package strategy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/utkuozdemir/pv-migrate/migration"
+	"github.com/utkuozdemir/pv-migrate/pvc"
+	"github.com/utkuozdemir/pv-migrate/rsync"
+	"github.com/utkuozdemir/pv-migrate/ssh"
+)
+
+const localDirMountPath = "/data"
+
+// Export copies the contents of the source PVC of the attempt into the given local directory.
+//
+// It runs sshd next to the PVC, port-forwards to it and runs rsync on the local machine against it.
+func Export(ctx context.Context, attempt *migration.Attempt, localDir string, logger *slog.Logger) error {
+	return runLocalDir(ctx, attempt, attempt.Migration.SourceInfo, localDir, true, logger)
+}
+
+// Import copies the contents of the given local directory into the destination PVC of the attempt.
+//
+// It runs sshd next to the PVC, port-forwards to it and runs rsync on the local machine against it.
+func Import(ctx context.Context, attempt *migration.Attempt, localDir string, logger *slog.Logger) error {
+	return runLocalDir(ctx, attempt, attempt.Migration.DestInfo, localDir, false, logger)
+}
+
+func runLocalDir(ctx context.Context, attempt *migration.Attempt, pvcInfo *pvc.Info,
+	localDir string, export bool, logger *slog.Logger,
+) error {
+	for _, binary := range []string{"rsync", "ssh"} {
+		if _, err := exec.LookPath(binary); err != nil {
+			return fmt.Errorf("%s binary not found", binary)
+		}
+	}
+
+	if pvcInfo == nil {
+		return errors.New("no PVC to transfer data from/to")
+	}
+
+	keyAlgorithm := attempt.Migration.Request.KeyAlgorithm
+
+	logger.Info("🔑 Generating SSH key pair", "algorithm", keyAlgorithm)
+
+	publicKey, privateKey, err := ssh.CreateSSHKeyPair(keyAlgorithm)
+	if err != nil {
+		return fmt.Errorf("failed to generate SSH key pair: %w", err)
+	}
+
+	releaseName := attempt.HelmReleaseNamePrefix
+	releaseNames := []string{releaseName}
+
+	doneCh := registerCleanupHook(attempt, releaseNames, logger)
+	defer cleanupAndReleaseHook(ctx, attempt, releaseNames, doneCh, logger)
+
+	if err = installLocalDirSshd(ctx, attempt, pvcInfo, releaseName, publicKey, export, logger); err != nil {
+		return fmt.Errorf("failed to install sshd: %w", err)
+	}
+
+	fwdPort, stopChan, err := portForwardToSshd(ctx, attempt.Migration.Request, pvcInfo, releaseName, logger)
+	if err != nil {
+		return fmt.Errorf("failed to port-forward to sshd: %w", err)
+	}
+
+	defer func() { stopChan <- struct{}{} }()
+
+	privateKeyFile, err := writePrivateKeyToTempFile(privateKey)
+	if err != nil {
+		return fmt.Errorf("failed to write private key to temp file: %w", err)
+	}
+
+	defer func() {
+		os.Remove(privateKeyFile)
+	}()
+
+	rsyncCmd, err := buildRsyncCmdLocalDir(attempt.Migration, localDir, fwdPort, privateKeyFile, export)
+	if err != nil {
+		return fmt.Errorf("failed to build rsync command: %w", err)
+	}
+
+	cmd := exec.Command("sh", "-c", rsyncCmd)
+
+	if err = runCmdLocal(ctx, attempt, cmd, logger); err != nil {
+		return fmt.Errorf("failed to run rsync command: %w", err)
+	}
+
+	return nil
+}
+
+func installLocalDirSshd(ctx context.Context, attempt *migration.Attempt, pvcInfo *pvc.Info,
+	releaseName, publicKey string, export bool, logger *slog.Logger,
+) error {
+	readOnly := export && attempt.Migration.Request.SourceMountReadOnly
+
+	vals := map[string]any{
+		"sshd": map[string]any{
+			"enabled":   true,
+			"namespace": pvcInfo.Claim.Namespace,
+			"publicKey": publicKey,
+			"pvcMounts": []map[string]any{
+				{
+					"name":      pvcInfo.Claim.Name,
+					"readOnly":  readOnly,
+					"mountPath": localDirMountPath,
+				},
+			},
+			"affinity": pvcInfo.AffinityHelmValues,
+		},
+	}
+
+	return installHelmChart(ctx, attempt, pvcInfo, releaseName, vals, logger)
+}
+
+func buildRsyncCmdLocalDir(mig *migration.Migration, localDir string,
+	port int, privateKeyFile string, export bool,
+) (string, error) {
+	request := mig.Request
+
+	rsyncCmd := rsync.Cmd{
+		Port:            port,
+		NoChown:         request.NoChown,
+		Delete:          request.DeleteExtraneousFiles,
+		Compress:        request.Compress,
+		SSHIdentityFile: privateKeyFile,
+	}
+
+	if export {
+		rsyncCmd.SrcUseSSH = true
+		rsyncCmd.SrcSSHHost = "localhost"
+		rsyncCmd.SrcSSHUser = sshUser(request)
+		rsyncCmd.SrcPath = localDirMountPath + "/" + request.Source.Path
+		rsyncCmd.DestPath = localDir
+	} else {
+		rsyncCmd.SrcPath = strings.TrimSuffix(localDir, "/") + "/"
+		rsyncCmd.DestUseSSH = true
+		rsyncCmd.DestSSHHost = "localhost"
+		rsyncCmd.DestSSHUser = sshUser(request)
+		rsyncCmd.DestPath = localDirMountPath + "/" + request.Dest.Path
+	}
+
+	cmd, err := rsyncCmd.Build()
+	if err != nil {
+		return "", fmt.Errorf("failed to build rsync command: %w", err)
+	}
+
+	return cmd, nil
+}
@@ -0,0 +1,54 @@
+package strategy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/utkuozdemir/pv-migrate/migration"
+)
+
+func TestBuildRsyncCmdLocalDir(t *testing.T) {
+	t.Parallel()
+
+	mig := migration.Migration{
+		Request: &migration.Request{
+			Source: &migration.PVCInfo{Path: "/"},
+			Dest:   &migration.PVCInfo{Path: "sub"},
+		},
+	}
+
+	cmd, err := buildRsyncCmdLocalDir(&mig, "./backup", 12345, "/tmp/key", true)
+	require.NoError(t, err)
+	assert.Equal(t, "rsync -av --info=progress2,misc0,flist0 --stats --no-inc-recursive -e "+
+		"'ssh -o StrictHostKeyChecking=no -o UserKnownHostsFile=/dev/null -o ConnectTimeout=5 -p 12345 "+
+		"-i /tmp/key' root@localhost:/data// ./backup", cmd)
+
+	cmd, err = buildRsyncCmdLocalDir(&mig, "./backup/", 12345, "/tmp/key", false)
+	require.NoError(t, err)
+	assert.Equal(t, "rsync -av --info=progress2,misc0,flist0 --stats --no-inc-recursive -e "+
+		"'ssh -o StrictHostKeyChecking=no -o UserKnownHostsFile=/dev/null -o ConnectTimeout=5 -p 12345 "+
+		"-i /tmp/key' ./backup/ root@localhost:/data/sub", cmd)
+}
+
+func TestBuildRsyncCmdLocalDirRootless(t *testing.T) {
+	t.Parallel()
+
+	mig := migration.Migration{
+		Request: &migration.Request{
+			Source:   &migration.PVCInfo{Path: "/"},
+			Dest:     &migration.PVCInfo{Path: "sub"},
+			NoChown:  true,
+			Rootless: true,
+		},
+	}
+
+	cmd, err := buildRsyncCmdLocalDir(&mig, "./backup", 12345, "/tmp/key", true)
+	require.NoError(t, err)
+	assert.Contains(t, cmd, " pv-migrate@localhost:/data// ./backup")
+
+	cmd, err = buildRsyncCmdLocalDir(&mig, "./backup/", 12345, "/tmp/key", false)
+	require.NoError(t, err)
+	assert.Contains(t, cmd, " ./backup/ pv-migrate@localhost:/data/sub")
+}
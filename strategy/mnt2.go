@@ -5,9 +5,8 @@ import (
 	"fmt"
 	"log/slog"
 
-	"github.com/utkuozdemir/pv-migrate/k8s"
 	"github.com/utkuozdemir/pv-migrate/migration"
-	"github.com/utkuozdemir/pv-migrate/rsync"
+	"github.com/utkuozdemir/pv-migrate/transfer"
 )
 
 type Mnt2 struct{}
@@ -32,21 +31,43 @@ func (r *Mnt2) canDo(t *migration.Migration) bool {
 	return sameNode || oneUnmounted || sourceInfo.SupportsROX || sourceInfo.SupportsRWX || destInfo.SupportsRWX
 }
 
+func (r *Mnt2) Score(mig *migration.Migration, _ *Facts) Score {
+	if !r.canDo(mig) {
+		return Score{Reasons: []string{"PVCs cannot be mounted in a single pod"}}
+	}
+
+	if mig.Request.Rootless && !mig.Request.SourceMountReadOnly {
+		return Score{Reasons: []string{
+			"the group of the files of the source would be changed to the fsGroup, as it is not mounted read-only",
+		}}
+	}
+
+	return Score{Value: mnt2ScoreValue, Reasons: []string{"PVCs can be mounted in a single pod, no network is needed"}}
+}
+
 func (r *Mnt2) Run(ctx context.Context, attempt *migration.Attempt, logger *slog.Logger) error {
 	mig := attempt.Migration
 	if !r.canDo(mig) {
 		return ErrUnaccepted
 	}
 
+	// the kubelet does not change the group of the files of the read-only volumes to the fsGroup
+	if mig.Request.Rootless && !mig.Request.SourceMountReadOnly {
+		logger.Debug("mnt2 strategy cannot mount the source next to the destination as a non-root user, " +
+			"as it is not mounted read-only")
+
+		return ErrUnaccepted
+	}
+
 	sourceInfo := attempt.Migration.SourceInfo
 	destInfo := attempt.Migration.DestInfo
 	namespace := sourceInfo.Claim.Namespace
 
 	node := determineTargetNode(mig)
 
-	rsyncCmd, err := buildRsyncCmdMnt2(mig)
+	transferCmd, err := buildTransferCmdMnt2(mig)
 	if err != nil {
-		return fmt.Errorf("failed to build rsync command: %w", err)
+		return err
 	}
 
 	vals := map[string]any{
@@ -65,8 +86,11 @@ func (r *Mnt2) Run(ctx context.Context, attempt *migration.Attempt, logger *slog
 					"mountPath": destMountPath,
 				},
 			},
-			"command":  rsyncCmd,
-			"affinity": sourceInfo.AffinityHelmValues,
+			"command":              transferCmd,
+			"suspend":              transferJobSuspended(mig.Request),
+			"filterRules":          mig.Request.FilterRules,
+			"filterRulesMountPath": filterRulesMountPath,
+			"affinity":             sourceInfo.AffinityHelmValues,
 		},
 	}
 
@@ -76,40 +100,22 @@ func (r *Mnt2) Run(ctx context.Context, attempt *migration.Attempt, logger *slog
 	doneCh := registerCleanupHook(attempt, releaseNames, logger)
 	defer cleanupAndReleaseHook(ctx, attempt, releaseNames, doneCh, logger)
 
-	err = installHelmChart(attempt, sourceInfo, releaseName, vals, logger)
+	err = installHelmChart(ctx, attempt, sourceInfo, releaseName, vals, logger)
 	if err != nil {
 		return fmt.Errorf("failed to install helm chart: %w", err)
 	}
 
-	showProgressBar := !mig.Request.NoProgressBar
 	kubeClient := mig.SourceInfo.ClusterClient.KubeClient
 	jobName := attempt.HelmReleaseNamePrefix + "-rsync"
 
-	if err = k8s.WaitForJobCompletion(ctx, kubeClient, namespace, jobName, showProgressBar, logger); err != nil {
-		return fmt.Errorf("failed to wait for job completion: %w", err)
-	}
-
-	return nil
+	return waitForTransferJob(ctx, mig, kubeClient, namespace, jobName, logger)
 }
 
-func buildRsyncCmdMnt2(mig *migration.Migration) (string, error) {
-	srcPath := srcMountPath + "/" + mig.Request.Source.Path
-	destPath := destMountPath + "/" + mig.Request.Dest.Path
-
-	rsyncCmd := rsync.Cmd{
-		NoChown:  mig.Request.NoChown,
-		Delete:   mig.Request.DeleteExtraneousFiles,
-		SrcPath:  srcPath,
-		DestPath: destPath,
-		Compress: mig.Request.Compress,
-	}
-
-	cmd, err := rsyncCmd.Build()
-	if err != nil {
-		return "", fmt.Errorf("failed to build rsync command: %w", err)
-	}
+func buildTransferCmdMnt2(mig *migration.Migration) (string, error) {
+	src := transfer.Endpoint{Path: srcMountPath + "/" + mig.Request.Source.Path}
+	dest := transfer.Endpoint{Path: destMountPath + "/" + mig.Request.Dest.Path}
 
-	return cmd, nil
+	return buildTransferCmd(mig, &src, &dest, "")
 }
 
 func determineTargetNode(t *migration.Migration) string {
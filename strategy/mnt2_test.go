@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/fake"
@@ -601,3 +602,31 @@ func buildTestClientWithAPIServerHost(apiServerHost string,
 		},
 	}
 }
+
+func TestMountsDest(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	src, err := pvc.New(ctx, buildTestClient(buildTestPVC("ns", "data")), "ns", "data")
+	require.NoError(t, err)
+
+	dst, err := pvc.New(ctx, buildTestClientWithAPIServerHost("https://10.0.0.1:6443",
+		buildTestPVC("ns", "data")), "ns", "data")
+	require.NoError(t, err)
+
+	mig := migration.Migration{SourceInfo: src, DestInfo: dst}
+	destVals := map[string]any{
+		"rsync": map[string]any{"pvcMounts": []map[string]any{{"name": "data", "mountPath": destMountPath}}},
+	}
+	srcVals := map[string]any{
+		"sshd": map[string]any{"pvcMounts": []map[string]any{{"name": "data", "mountPath": srcMountPath}}},
+	}
+	probeVals := map[string]any{"rsync": map[string]any{"command": "true"}}
+
+	assert.True(t, mountsDest(&mig, dst, destVals))
+	// the PVC of the same name is in another cluster
+	assert.False(t, mountsDest(&mig, src, srcVals))
+	assert.False(t, mountsDest(&mig, dst, probeVals))
+}
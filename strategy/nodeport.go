@@ -0,0 +1,111 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"strconv"
+
+	"github.com/utkuozdemir/pv-migrate/k8s"
+	"github.com/utkuozdemir/pv-migrate/migration"
+	"github.com/utkuozdemir/pv-migrate/ssh"
+)
+
+const sshdServicePort = 22
+
+type NodePort struct{}
+
+func (r *NodePort) Score(_ *migration.Migration, facts *Facts) Score {
+	score := Score{Value: nodePortScoreValue, Reasons: []string{
+		"requires the node ports of the source cluster to be reachable from the destination",
+	}}
+	penalizeNetworkPolicies(&score, facts)
+
+	return score
+}
+
+func (r *NodePort) Run(ctx context.Context, attempt *migration.Attempt, logger *slog.Logger) error {
+	mig := attempt.Migration
+
+	destInfo := mig.DestInfo
+	destNs := destInfo.Claim.Namespace
+	keyAlgorithm := mig.Request.KeyAlgorithm
+
+	logger.Info("🔑 Generating SSH key pair", "algorithm", keyAlgorithm)
+
+	publicKey, privateKey, err := ssh.CreateSSHKeyPair(keyAlgorithm)
+	if err != nil {
+		return fmt.Errorf("failed to create ssh key pair: %w", err)
+	}
+
+	privateKeyMountPath := "/tmp/id_" + keyAlgorithm
+
+	srcReleaseName := attempt.HelmReleaseNamePrefix + "-src"
+	destReleaseName := attempt.HelmReleaseNamePrefix + "-dest"
+	probeReleaseName := attempt.HelmReleaseNamePrefix + "-probe"
+	releaseNames := []string{srcReleaseName, probeReleaseName, destReleaseName}
+
+	doneCh := registerCleanupHook(attempt, releaseNames, logger)
+	defer cleanupAndReleaseHook(ctx, attempt, releaseNames, doneCh, logger)
+
+	err = installOnSource(ctx, attempt, srcReleaseName, publicKey, srcMountPath, "NodePort", logger)
+	if err != nil {
+		return fmt.Errorf("failed to install on source: %w", err)
+	}
+
+	sshHost, sshPort, err := getNodePortAddress(ctx, attempt, srcReleaseName)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("🔌 Using node port address", "address", net.JoinHostPort(sshHost, strconv.Itoa(sshPort)))
+
+	sshTargetHost := formatSSHTargetHost(sshHost)
+	if mig.Request.DestHostOverride != "" {
+		sshTargetHost = mig.Request.DestHostOverride
+	}
+
+	if err = probeSSHD(ctx, attempt, probeReleaseName, sshTargetHost, sshPort, logger); err != nil {
+		return err
+	}
+
+	err = installOnDest(ctx, attempt, destReleaseName, privateKey, privateKeyMountPath,
+		sshTargetHost, sshPort, srcMountPath, destMountPath, logger)
+	if err != nil {
+		return fmt.Errorf("failed to install on dest: %w", err)
+	}
+
+	kubeClient := destInfo.ClusterClient.KubeClient
+	jobName := destReleaseName + "-rsync"
+
+	return waitForTransferJob(ctx, mig, kubeClient, destNs, jobName, logger)
+}
+
+// getNodePortAddress returns the address of the node which runs the source sshd pod,
+// along with the node port allocated to the sshd service.
+func getNodePortAddress(ctx context.Context, attempt *migration.Attempt, srcReleaseName string) (string, int, error) {
+	mig := attempt.Migration
+	sourceInfo := mig.SourceInfo
+	sourceKubeClient := sourceInfo.ClusterClient.KubeClient
+	svcName := srcReleaseName + "-sshd"
+
+	nodePort, err := k8s.GetServiceNodePort(ctx, sourceKubeClient,
+		sourceInfo.Claim.Namespace, svcName, sshdServicePort)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to get node port: %w", err)
+	}
+
+	sshdPod, err := getSshdPodForHelmRelease(ctx, sourceInfo, srcReleaseName)
+	if err != nil {
+		return "", 0, err
+	}
+
+	nodeAddress, err := k8s.GetNodeAddress(ctx, sourceKubeClient,
+		sshdPod.Spec.NodeName, mig.Request.NodePortAddressType)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to get node address: %w", err)
+	}
+
+	return nodeAddress, nodePort, nil
+}
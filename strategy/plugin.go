@@ -0,0 +1,399 @@
+package strategy
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/schollz/progressbar/v3"
+
+	"github.com/utkuozdemir/pv-migrate/migration"
+	"github.com/utkuozdemir/pv-migrate/pvc"
+	"github.com/utkuozdemir/pv-migrate/rsync/progress"
+)
+
+const (
+	// PluginPrefix is the prefix of the executables on PATH which are loaded as external strategies.
+	PluginPrefix = "pv-migrate-strategy-"
+
+	// PluginProtocolVersion is the version of the protocol the external strategies are spoken to with.
+	PluginProtocolVersion = 1
+
+	PluginEventLog      = "log"
+	PluginEventProgress = "progress"
+	PluginEventResult   = "result"
+
+	PluginResultSuccess     = "success"
+	PluginResultUnaccepted  = "unaccepted"
+	PluginResultUnreachable = "unreachable"
+	PluginResultFailed      = "failed"
+
+	pluginExecutableMode         = 0o111
+	pluginTerminationGracePeriod = 30 * time.Second
+)
+
+var (
+	pluginNameRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+	loadPluginsOnce sync.Once
+)
+
+// Plugin is an external strategy, an executable which receives the migration as a PluginRequest in JSON on its stdin
+// and writes PluginEvents as JSON lines to its stdout.
+type Plugin struct {
+	Name string
+	Path string
+}
+
+// PluginRequest is the description of the migration attempt which is written to the stdin of a plugin.
+type PluginRequest struct {
+	ProtocolVersion       int            `json:"protocolVersion"`
+	AttemptID             string         `json:"attemptId"`
+	Source                *PluginPVCInfo `json:"source"`
+	Dest                  *PluginPVCInfo `json:"dest"`
+	DeleteExtraneousFiles bool           `json:"deleteExtraneousFiles"`
+	NoChown               bool           `json:"noChown"`
+	Compress              bool           `json:"compress"`
+	SourceMountReadOnly   bool           `json:"sourceMountReadOnly"`
+	SkipCleanup           bool           `json:"skipCleanup"`
+	Includes              []string       `json:"includes,omitempty"`
+	Excludes              []string       `json:"excludes,omitempty"`
+	FilterRules           string         `json:"filterRules,omitempty"`
+	BwLimit               string         `json:"bwLimit,omitempty"`
+	Preserve              []string       `json:"preserve,omitempty"`
+	LargeFileModes        []string       `json:"largeFileModes,omitempty"`
+	Chown                 string         `json:"chown,omitempty"`
+	UserMap               []string       `json:"userMap,omitempty"`
+	GroupMap              []string       `json:"groupMap,omitempty"`
+	Parallel              int            `json:"parallel,omitempty"`
+	AllowVanishedFiles    bool           `json:"allowVanishedFiles,omitempty"`
+}
+
+// PluginPVCInfo describes a PVC of the migration to a plugin.
+type PluginPVCInfo struct {
+	Kubeconfig       string `json:"kubeconfig,omitempty"`
+	Context          string `json:"context,omitempty"`
+	Namespace        string `json:"namespace"`
+	Name             string `json:"name"`
+	Path             string `json:"path"`
+	VolumeName       string `json:"volumeName,omitempty"`
+	StorageClassName string `json:"storageClassName,omitempty"`
+	MountedNode      string `json:"mountedNode,omitempty"`
+}
+
+// PluginEvent is a line written by a plugin to its stdout.
+type PluginEvent struct {
+	Type string `json:"type"`
+
+	// Level and Message are set on log events. Message is also the error message of unsuccessful results.
+	Level   string `json:"level,omitempty"`
+	Message string `json:"message,omitempty"`
+
+	// Transferred and Total are set on progress events, in bytes.
+	Transferred int64 `json:"transferred,omitempty"`
+	Total       int64 `json:"total,omitempty"`
+
+	// Result is set on result events, which must be the last event.
+	Result string `json:"result,omitempty"`
+}
+
+// LoadPlugins discovers the pv-migrate-strategy-<name> executables on PATH and registers them as strategies,
+// appending them to AllStrategies. The executables which would shadow a built-in strategy are ignored.
+//
+// It is safe to call multiple times, the plugins are only discovered once.
+func LoadPlugins() {
+	loadPluginsOnce.Do(func() {
+		for _, plugin := range discoverPlugins(filepath.SplitList(os.Getenv("PATH"))) {
+			AllStrategies = append(AllStrategies, plugin.Name)
+			nameToStrategy[plugin.Name] = plugin
+		}
+	})
+}
+
+// discoverPlugins returns the plugins in the given directories sorted by name.
+// Like in a shell, the first executable found for a name wins.
+func discoverPlugins(dirs []string) []*Plugin {
+	nameToPlugin := make(map[string]*Plugin)
+
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+
+		for _, entry := range entries {
+			name, ok := pluginName(entry.Name())
+			if !ok {
+				continue
+			}
+
+			if _, found := nameToPlugin[name]; found {
+				continue
+			}
+
+			path := filepath.Join(dir, entry.Name())
+			if !isExecutable(path) {
+				continue
+			}
+
+			nameToPlugin[name] = &Plugin{Name: name, Path: path}
+		}
+	}
+
+	plugins := make([]*Plugin, 0, len(nameToPlugin))
+	for _, plugin := range nameToPlugin {
+		plugins = append(plugins, plugin)
+	}
+
+	slices.SortFunc(plugins, func(a, b *Plugin) int { return strings.Compare(a.Name, b.Name) })
+
+	return plugins
+}
+
+func pluginName(fileName string) (string, bool) {
+	name, ok := strings.CutPrefix(fileName, PluginPrefix)
+	if !ok {
+		return "", false
+	}
+
+	if runtime.GOOS == "windows" {
+		name = strings.TrimSuffix(name, ".exe")
+	}
+
+	if _, builtin := nameToStrategy[name]; builtin || name == AutoStrategy || !pluginNameRegex.MatchString(name) {
+		return "", false
+	}
+
+	return name, true
+}
+
+func isExecutable(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return false
+	}
+
+	return runtime.GOOS == "windows" || info.Mode().Perm()&pluginExecutableMode != 0
+}
+
+func (p *Plugin) Run(ctx context.Context, attempt *migration.Attempt, logger *slog.Logger) error {
+	if attempt.Migration.Request.Window != nil {
+		logger.Debug("strategy plugins cannot be paused outside the window", "plugin", p.Name)
+
+		return ErrUnaccepted
+	}
+
+	if attempt.Migration.Request.ChangesLogPath != "" {
+		logger.Debug("strategy plugins cannot record the changes of the files", "plugin", p.Name)
+
+		return ErrUnaccepted
+	}
+
+	if attempt.Migration.Request.Rootless {
+		logger.Debug("strategy plugins cannot run their pods as a non-root user", "plugin", p.Name)
+
+		return ErrUnaccepted
+	}
+
+	input, err := json.Marshal(buildPluginRequest(attempt))
+	if err != nil {
+		return fmt.Errorf("failed to encode plugin request: %w", err)
+	}
+
+	logger = logger.With("plugin", p.Path)
+
+	var stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, p.Path)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stderr = &stderr
+	// give the plugin a chance to clean up when the migration is cancelled
+	cmd.Cancel = func() error { return cmd.Process.Signal(os.Interrupt) }
+	cmd.WaitDelay = pluginTerminationGracePeriod
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to get stdout of plugin: %w", err)
+	}
+
+	logger.Info("🔌 Running strategy plugin")
+
+	if err = cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start plugin: %w", err)
+	}
+
+	showProgressBar := !attempt.Migration.Request.NoProgressBar &&
+		ctx.Value(progress.CanDisplayProgressBarContextKey{}) != nil
+
+	result, readErr := readPluginEvents(ctx, stdout, showProgressBar, logger)
+
+	// drain the remaining output, so that the plugin does not block on writing it
+	_, _ = io.Copy(io.Discard, stdout)
+
+	waitErr := cmd.Wait()
+
+	if readErr != nil {
+		return fmt.Errorf("failed to read plugin events: %w", readErr)
+	}
+
+	return pluginResultToError(result, waitErr, strings.TrimSpace(stderr.String()))
+}
+
+func buildPluginRequest(attempt *migration.Attempt) *PluginRequest {
+	mig := attempt.Migration
+	req := mig.Request
+
+	return &PluginRequest{
+		ProtocolVersion:       PluginProtocolVersion,
+		AttemptID:             attempt.ID,
+		Source:                buildPluginPVCInfo(req.Source, mig.SourceInfo),
+		Dest:                  buildPluginPVCInfo(req.Dest, mig.DestInfo),
+		DeleteExtraneousFiles: req.DeleteExtraneousFiles,
+		NoChown:               req.NoChown,
+		Compress:              req.Compress,
+		SourceMountReadOnly:   req.SourceMountReadOnly,
+		SkipCleanup:           req.SkipCleanup,
+		Includes:              req.Includes,
+		Excludes:              req.Excludes,
+		FilterRules:           req.FilterRules,
+		BwLimit:               req.BwLimit,
+		Preserve:              req.Preserve.Options(),
+		LargeFileModes:        req.LargeFile.Modes(),
+		Chown:                 req.Ownership.Chown,
+		UserMap:               req.Ownership.UserMap,
+		GroupMap:              req.Ownership.GroupMap,
+		Parallel:              req.Parallel,
+		AllowVanishedFiles:    req.AllowVanishedFiles,
+	}
+}
+
+func buildPluginPVCInfo(pvcInfo *migration.PVCInfo, info *pvc.Info) *PluginPVCInfo {
+	claim := info.Claim
+
+	result := PluginPVCInfo{
+		Kubeconfig:  pvcInfo.KubeconfigPath,
+		Context:     pvcInfo.Context,
+		Namespace:   claim.Namespace,
+		Name:        claim.Name,
+		Path:        pvcInfo.Path,
+		VolumeName:  claim.Spec.VolumeName,
+		MountedNode: info.MountedNode,
+	}
+
+	if claim.Spec.StorageClassName != nil {
+		result.StorageClassName = *claim.Spec.StorageClassName
+	}
+
+	return &result
+}
+
+// readPluginEvents handles the events of the plugin until its result, which is returned.
+// If the output ends without a result, nil is returned.
+//
+//nolint:cyclop
+func readPluginEvents(ctx context.Context, stdout io.Reader,
+	showProgressBar bool, logger *slog.Logger,
+) (*PluginEvent, error) {
+	var progressBar *progressbar.ProgressBar
+
+	scanner := bufio.NewScanner(stdout)
+
+	for scanner.Scan() {
+		var event PluginEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			logger.Debug("ignoring unparseable plugin output", "line", scanner.Text(), "error", err)
+
+			continue
+		}
+
+		switch event.Type {
+		case PluginEventLog:
+			var level slog.Level
+			if err := level.UnmarshalText([]byte(event.Level)); err != nil {
+				level = slog.LevelInfo
+			}
+
+			logger.Log(ctx, level, event.Message, "source", "plugin")
+		case PluginEventProgress:
+			if !showProgressBar {
+				logger.Debug("plugin progress", "transferred", event.Transferred, "total", event.Total)
+
+				continue
+			}
+
+			if progressBar == nil {
+				progressBar = progress.NewBar(event.Total, "📂 Copying data...")
+			}
+
+			progressBar.ChangeMax64(event.Total)
+
+			if err := progressBar.Set64(event.Transferred); err != nil {
+				logger.Debug("failed to update progress bar", "error", err)
+			}
+		case PluginEventResult:
+			if progressBar != nil && event.Result == PluginResultSuccess {
+				_ = progressBar.Finish()
+			}
+
+			return &event, nil
+		default:
+			logger.Debug("ignoring unknown plugin event", "type", event.Type)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to scan plugin output: %w", err)
+	}
+
+	return nil, nil //nolint:nilnil
+}
+
+func pluginResultToError(result *PluginEvent, waitErr error, stderr string) error {
+	if result == nil {
+		if waitErr != nil {
+			return fmt.Errorf("plugin exited without a result: %w: %s", waitErr, stderr)
+		}
+
+		return errors.New("plugin exited without a result")
+	}
+
+	message := result.Message
+	if message == "" {
+		message = stderr
+	}
+
+	switch result.Result {
+	case PluginResultSuccess:
+		if waitErr != nil {
+			return fmt.Errorf("plugin reported success but exited with an error: %w", waitErr)
+		}
+
+		return nil
+	case PluginResultUnaccepted:
+		return ErrUnaccepted
+	case PluginResultUnreachable:
+		return fmt.Errorf("%w: %s", ErrUnreachable, message)
+	case PluginResultFailed:
+		return fmt.Errorf("plugin failed: %s", message)
+	default:
+		return fmt.Errorf("plugin reported an unknown result: %s", result.Result)
+	}
+}
@@ -0,0 +1,164 @@
+package strategy
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/neilotoole/slogt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/utkuozdemir/pv-migrate/migration"
+	"github.com/utkuozdemir/pv-migrate/pvc"
+)
+
+func TestDiscoverPlugins(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("plugins are detected by the file mode")
+	}
+
+	dir1 := t.TempDir()
+	dir2 := t.TempDir()
+
+	writePlugin(t, dir1, PluginPrefix+"replication", "")
+	writePlugin(t, dir2, PluginPrefix+"replication", "")
+	writePlugin(t, dir2, PluginPrefix+"backup", "")
+	writePlugin(t, dir2, PluginPrefix+SvcStrategy, "")
+	writePlugin(t, dir2, PluginPrefix+AutoStrategy, "")
+	writePlugin(t, dir2, PluginPrefix+"Invalid_Name", "")
+	writePlugin(t, dir2, "some-other-binary", "")
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir2, PluginPrefix+"not-executable"), nil, 0o600))
+
+	plugins := discoverPlugins([]string{"", dir1, filepath.Join(dir1, "missing"), dir2})
+
+	assert.Equal(t, []*Plugin{
+		{Name: "backup", Path: filepath.Join(dir2, PluginPrefix+"backup")},
+		{Name: "replication", Path: filepath.Join(dir1, PluginPrefix+"replication")},
+	}, plugins)
+}
+
+func TestPluginRun(t *testing.T) {
+	t.Parallel()
+
+	if runtime.GOOS == "windows" {
+		t.Skip("the test plugins are shell scripts")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	pvcA := buildTestPVC("namespace1", "pvc1", corev1.ReadWriteOnce)
+	pvcB := buildTestPVC("namespace2", "pvc2", corev1.ReadWriteOnce)
+	cli := buildTestClient(pvcA, pvcB)
+
+	src, err := pvc.New(ctx, cli, "namespace1", "pvc1")
+	require.NoError(t, err)
+
+	dst, err := pvc.New(ctx, cli, "namespace2", "pvc2")
+	require.NoError(t, err)
+
+	attempt := migration.Attempt{
+		ID: "abcde",
+		Migration: &migration.Migration{
+			Request: &migration.Request{
+				Source:        &migration.PVCInfo{Path: "/data"},
+				Dest:          &migration.PVCInfo{Path: "/"},
+				NoProgressBar: true,
+			},
+			SourceInfo: src,
+			DestInfo:   dst,
+		},
+	}
+
+	dir := t.TempDir()
+	requestFile := filepath.Join(dir, "request.json")
+
+	tests := []struct {
+		name   string
+		script string
+		check  func(t *testing.T, err error)
+	}{
+		{
+			name: "success",
+			script: `cat > ` + requestFile + `
+echo 'not json'
+echo '{"type":"log","level":"warn","message":"replicating"}'
+echo '{"type":"progress","transferred":50,"total":100}'
+echo '{"type":"result","result":"success"}'`,
+			check: func(t *testing.T, err error) {
+				t.Helper()
+
+				require.NoError(t, err)
+
+				request, readErr := os.ReadFile(requestFile)
+				require.NoError(t, readErr)
+				assert.JSONEq(t, `{"protocolVersion":1,"attemptId":"abcde",`+
+					`"source":{"namespace":"namespace1","name":"pvc1","path":"/data"},`+
+					`"dest":{"namespace":"namespace2","name":"pvc2","path":"/"},`+
+					`"deleteExtraneousFiles":false,"noChown":false,"compress":false,`+
+					`"sourceMountReadOnly":false,"skipCleanup":false}`, string(request))
+			},
+		},
+		{
+			name:   "unaccepted",
+			script: `echo '{"type":"result","result":"unaccepted"}'`,
+			check: func(t *testing.T, err error) {
+				t.Helper()
+
+				require.ErrorIs(t, err, ErrUnaccepted)
+			},
+		},
+		{
+			name:   "unreachable",
+			script: `echo '{"type":"result","result":"unreachable","message":"array is offline"}'`,
+			check: func(t *testing.T, err error) {
+				t.Helper()
+
+				require.ErrorIs(t, err, ErrUnreachable)
+				assert.ErrorContains(t, err, "array is offline")
+			},
+		},
+		{
+			name:   "failed",
+			script: `echo 'quota exceeded' >&2; echo '{"type":"result","result":"failed"}'; exit 1`,
+			check: func(t *testing.T, err error) {
+				t.Helper()
+
+				assert.EqualError(t, err, "plugin failed: quota exceeded")
+			},
+		},
+		{
+			name:   "no result",
+			script: `exit 0`,
+			check: func(t *testing.T, err error) {
+				t.Helper()
+
+				assert.EqualError(t, err, "plugin exited without a result")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		path := writePlugin(t, dir, PluginPrefix+tt.name, tt.script)
+		plugin := Plugin{Name: tt.name, Path: path}
+
+		tt.check(t, plugin.Run(ctx, &attempt, slogt.New(t)))
+	}
+}
+
+func writePlugin(t *testing.T, dir, name, script string) string {
+	t.Helper()
+
+	path := filepath.Join(dir, name)
+
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0o700)) //nolint:gosec
+
+	return path
+}
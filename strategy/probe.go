@@ -0,0 +1,95 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/utkuozdemir/pv-migrate/k8s"
+	"github.com/utkuozdemir/pv-migrate/migration"
+)
+
+const probeHandshakeTimeout = 10 * time.Second
+
+// probeSSHD runs a short-lived job next to where the transfer job will run, which attempts an SSH handshake
+// with the sshd within a short deadline.
+//
+// It is run before installing the transfer job, so that a blocked network is detected within seconds
+// instead of after all the retries of the transfer command. If the handshake fails, an error wrapping
+// ErrUnreachable is returned.
+func probeSSHD(ctx context.Context, attempt *migration.Attempt, releaseName,
+	host string, port int, logger *slog.Logger,
+) error {
+	destInfo := attempt.Migration.DestInfo
+	namespace := destInfo.Claim.Namespace
+
+	// ssh-keyscan cannot connect over the jump hosts
+	if jumpHosts := attempt.Migration.Request.SSHOptions.ProxyJump; len(jumpHosts) > 0 {
+		logger.Info("📡 Skipping the connectivity probe, sshd is reached over jump hosts", "jump_hosts", jumpHosts)
+
+		return nil
+	}
+
+	if port == 0 {
+		port = sshdServicePort
+	}
+
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	address := net.JoinHostPort(host, strconv.Itoa(port))
+
+	logger.Info("📡 Probing connectivity to sshd", "address", address)
+
+	vals := map[string]any{
+		"rsync": map[string]any{
+			"enabled":   true,
+			"namespace": namespace,
+			"command":   buildProbeCmd(host, port),
+			"affinity":  destInfo.AffinityHelmValues,
+		},
+	}
+
+	if err := installHelmChart(ctx, attempt, destInfo, releaseName, vals, logger); err != nil {
+		return fmt.Errorf("failed to install probe: %w", err)
+	}
+
+	pod, err := k8s.WaitForJobPodTermination(ctx, destInfo.ClusterClient.KubeClient, namespace, releaseName+"-rsync")
+	if err != nil {
+		return fmt.Errorf("failed to wait for probe: %w", err)
+	}
+
+	if pod.Status.Phase != corev1.PodSucceeded {
+		return fmt.Errorf("%w: %s", ErrUnreachable, probeFailureReason(pod, address))
+	}
+
+	logger.Info("📡 sshd is reachable", "address", address)
+
+	return nil
+}
+
+// buildProbeCmd builds the command which succeeds only if ssh-keyscan completes an SSH handshake with the sshd,
+// writing the reason into the termination message of the container otherwise.
+func buildProbeCmd(host string, port int) string {
+	timeoutSeconds := int(probeHandshakeTimeout.Seconds())
+	quotedHost := "'" + strings.ReplaceAll(host, "'", `'\''`) + "'"
+
+	return fmt.Sprintf(`out=$(ssh-keyscan -T %d -p %d %s 2>&1); `+
+		`echo "$out" | grep -Eq '^[^#].* (ssh|ecdsa)-' || `+
+		`{ echo "no SSH handshake within %ds: $out" | tee /dev/termination-log; false; }`,
+		timeoutSeconds, port, quotedHost, timeoutSeconds)
+}
+
+func probeFailureReason(pod *corev1.Pod, address string) string {
+	for _, status := range pod.Status.ContainerStatuses {
+		if terminated := status.State.Terminated; terminated != nil && terminated.Message != "" {
+			return address + ": " + strings.TrimSpace(terminated.Message)
+		}
+	}
+
+	return address + ": probe pod " + pod.Namespace + "/" + pod.Name + " failed"
+}
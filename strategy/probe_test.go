@@ -0,0 +1,40 @@
+package strategy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestBuildProbeCmd(t *testing.T) {
+	t.Parallel()
+
+	cmd := buildProbeCmd("pv-migrate-abcde-src-sshd.ns'1", 2222)
+	assert.Contains(t, cmd, `ssh-keyscan -T 10 -p 2222 'pv-migrate-abcde-src-sshd.ns'\''1' 2>&1`)
+	assert.Contains(t, cmd, "tee /dev/termination-log")
+}
+
+func TestProbeFailureReason(t *testing.T) {
+	t.Parallel()
+
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "probe"},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodFailed,
+			ContainerStatuses: []corev1.ContainerStatus{
+				{
+					State: corev1.ContainerState{
+						Terminated: &corev1.ContainerStateTerminated{ExitCode: 1, Message: "no SSH handshake within 10s: \n"},
+					},
+				},
+			},
+		},
+	}
+
+	assert.Equal(t, "sshd:22: no SSH handshake within 10s:", probeFailureReason(&pod, "sshd:22"))
+
+	pod.Status.ContainerStatuses = nil
+	assert.Equal(t, "sshd:22: probe pod ns/probe failed", probeFailureReason(&pod, "sshd:22"))
+}
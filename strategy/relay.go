@@ -0,0 +1,329 @@
+package strategy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	"github.com/utkuozdemir/pv-migrate/k8s"
+	"github.com/utkuozdemir/pv-migrate/migration"
+	"github.com/utkuozdemir/pv-migrate/ssh"
+	"github.com/utkuozdemir/pv-migrate/transfer"
+)
+
+const (
+	relayTunnelPortMin      = 20000
+	relayTunnelPortRange    = 20000
+	relayTunnelPortAttempts = 3
+	relayTunnelPollInterval = 2 * time.Second
+	relayTunnelTimeout      = time.Minute
+	relayKeepAliveSecs      = 15
+	relayConnectTimeout     = 10
+	relayReconnectSecs      = 5
+
+	relayKeyMountPath         = "/tmp/id_relay"
+	relayControlSocket        = "/tmp/relay.sock"
+	relayTunnelEstablishedMsg = "tunnel established"
+)
+
+// errRelayPortInUse is returned when the tunnel port cannot be forwarded on the bastion.
+var errRelayPortInUse = errors.New("tunnel port is in use on the relay host")
+
+var relayAddressRegex = regexp.MustCompile(`^[a-zA-Z0-9._-]+@[a-zA-Z0-9.:\[\]_-]+$`)
+
+// Relay relays the traffic over a user-supplied SSH bastion host which both clusters can connect out to.
+//
+// A tunnel pod in the source cluster opens a reverse tunnel on the bastion to the source sshd,
+// then the transfer job in the destination cluster connects to the other end of that tunnel,
+// jumping over the bastion.
+type Relay struct{}
+
+// relayHost is the address of the bastion, parsed from user@host[:port].
+type relayHost struct {
+	user string
+	host string
+	port int
+}
+
+func (r *Relay) Score(mig *migration.Migration, _ *Facts) Score {
+	if mig.Request.RelaySSH == "" {
+		return Score{Reasons: []string{"no relay host is given"}}
+	}
+
+	return Score{Value: relayScoreValue, Reasons: []string{
+		"the traffic flows over the relay host, which both clusters need to be able to connect to",
+	}}
+}
+
+//nolint:funlen
+func (r *Relay) Run(ctx context.Context, attempt *migration.Attempt, logger *slog.Logger) error {
+	mig := attempt.Migration
+	if mig.Request.RelaySSH == "" {
+		return ErrUnaccepted
+	}
+
+	relay, err := parseRelayHost(mig.Request.RelaySSH)
+	if err != nil {
+		return err
+	}
+
+	relayKeyBytes, err := os.ReadFile(mig.Request.RelaySSHKeyPath)
+	if err != nil {
+		return fmt.Errorf("failed to read relay ssh key: %w", err)
+	}
+
+	relayKey := string(relayKeyBytes)
+	keyAlgorithm := mig.Request.KeyAlgorithm
+
+	logger.Info("🔑 Generating SSH key pair", "algorithm", keyAlgorithm)
+
+	// the key of the bastion is only mounted into the pods which connect to it, the sshd gets a key of its own
+	publicKey, privateKey, err := ssh.CreateSSHKeyPair(keyAlgorithm)
+	if err != nil {
+		return fmt.Errorf("failed to create ssh key pair: %w", err)
+	}
+
+	privateKeyMountPath := "/tmp/id_" + keyAlgorithm
+
+	srcReleaseName := attempt.HelmReleaseNamePrefix + "-src"
+	tunnelReleaseName := attempt.HelmReleaseNamePrefix + "-tunnel"
+	destReleaseName := attempt.HelmReleaseNamePrefix + "-dest"
+	releaseNames := []string{srcReleaseName, tunnelReleaseName, destReleaseName}
+
+	doneCh := registerCleanupHook(attempt, releaseNames, logger)
+	defer cleanupAndReleaseHook(ctx, attempt, releaseNames, doneCh, logger)
+
+	err = installOnSource(ctx, attempt, srcReleaseName, publicKey, srcMountPath, "ClusterIP", logger)
+	if err != nil {
+		return fmt.Errorf("failed to install on source: %w", err)
+	}
+
+	sshdHost := srcReleaseName + "-sshd." + mig.SourceInfo.Claim.Namespace
+
+	tunnelPort, err := r.openTunnel(ctx, attempt, tunnelReleaseName, relay, sshdHost, relayKey, logger)
+	if err != nil {
+		return err
+	}
+
+	src := transfer.Endpoint{
+		Path:    srcMountPath + "/" + mig.Request.Source.Path,
+		SSHHost: "localhost",
+		SSHPort: tunnelPort,
+	}
+	dest := transfer.Endpoint{Path: destMountPath + "/" + mig.Request.Dest.Path}
+
+	transferCmd, err := buildTransferCmdWithProxy(mig, &src, &dest, privateKeyMountPath,
+		buildRelayProxyCmd(relay, relayKeyMountPath))
+	if err != nil {
+		return err
+	}
+
+	destInfo := mig.DestInfo
+	destNs := destInfo.Claim.Namespace
+
+	vals := map[string]any{
+		"rsync": map[string]any{
+			"enabled":             true,
+			"namespace":           destNs,
+			"privateKeyMount":     true,
+			"privateKey":          privateKey,
+			"privateKeyMountPath": privateKeyMountPath,
+			"proxyKey":            relayKey,
+			"proxyKeyMountPath":   relayKeyMountPath,
+			"pvcMounts": []map[string]any{
+				{
+					"name":      destInfo.Claim.Name,
+					"mountPath": destMountPath,
+				},
+			},
+			"command":              transferCmd,
+			"suspend":              transferJobSuspended(mig.Request),
+			"filterRules":          mig.Request.FilterRules,
+			"filterRulesMountPath": filterRulesMountPath,
+			"affinity":             destInfo.AffinityHelmValues,
+		},
+	}
+
+	if err = installHelmChart(ctx, attempt, destInfo, destReleaseName, vals, logger); err != nil {
+		return fmt.Errorf("failed to install on dest: %w", err)
+	}
+
+	return waitForTransferJob(ctx, mig, destInfo.ClusterClient.KubeClient, destNs, destReleaseName+"-rsync", logger)
+}
+
+// openTunnel opens the reverse tunnel on a random port of the bastion and returns the port.
+//
+// The port might already be bound on the bastion, which is shared with other users,
+// so another one is tried if the forwarding fails.
+func (r *Relay) openTunnel(ctx context.Context, attempt *migration.Attempt, releaseName string,
+	relay *relayHost, sshdHost, relayKey string, logger *slog.Logger,
+) (int, error) {
+	var err error
+
+	for range relayTunnelPortAttempts {
+		tunnelPort := relayTunnelPortMin + rand.IntN(relayTunnelPortRange) //nolint:gosec
+
+		logger.Info("🚇 Opening reverse tunnel on the relay host", "relay", relay.address(), "port", tunnelPort)
+
+		err = r.installTunnel(ctx, attempt, releaseName, relay, tunnelPort, sshdHost, relayKey, logger)
+		if !errors.Is(err, errRelayPortInUse) {
+			return tunnelPort, err
+		}
+
+		logger.Warn("🔶 Port is in use on the relay host", "port", tunnelPort)
+
+		// the release is installed again with the next port
+		if cleanupErr := cleanupForPVC(releaseName, attempt.Migration.Request.HelmTimeout,
+			attempt.Migration.SourceInfo, logger); cleanupErr != nil {
+			return 0, cleanupErr
+		}
+	}
+
+	return 0, err
+}
+
+// installTunnel runs the job in the source cluster which keeps the reverse tunnel to the sshd open on the bastion,
+// until it is uninstalled. It returns when the tunnel is established.
+func (r *Relay) installTunnel(ctx context.Context, attempt *migration.Attempt, releaseName string,
+	relay *relayHost, tunnelPort int, sshdHost, relayKey string, logger *slog.Logger,
+) error {
+	sourceInfo := attempt.Migration.SourceInfo
+	namespace := sourceInfo.Claim.Namespace
+	kubeClient := sourceInfo.ClusterClient.KubeClient
+
+	vals := map[string]any{
+		"rsync": map[string]any{
+			"enabled":             true,
+			"namespace":           namespace,
+			"privateKeyMount":     true,
+			"privateKey":          relayKey,
+			"privateKeyMountPath": relayKeyMountPath,
+			"command":             buildRelayTunnelCmd(relay, tunnelPort, sshdHost, relayKeyMountPath),
+		},
+	}
+
+	if err := installHelmChart(ctx, attempt, sourceInfo, releaseName, vals, logger); err != nil {
+		return fmt.Errorf("failed to install tunnel: %w", err)
+	}
+
+	pod, err := k8s.WaitForPod(ctx, kubeClient, namespace, "job-name="+releaseName+"-rsync")
+	if err != nil {
+		return fmt.Errorf("failed to wait for tunnel pod: %w", err)
+	}
+
+	if err = wait.PollUntilContextTimeout(ctx, relayTunnelPollInterval, relayTunnelTimeout, true,
+		func(ctx context.Context) (bool, error) {
+			logs, err := kubeClient.CoreV1().Pods(namespace).GetLogs(pod.Name, &corev1.PodLogOptions{}).DoRaw(ctx)
+			if err != nil {
+				return false, fmt.Errorf("failed to get logs: %w", err)
+			}
+
+			if strings.Contains(string(logs), relayTunnelEstablishedMsg) {
+				return true, nil
+			}
+
+			if pod, err = kubeClient.CoreV1().Pods(namespace).Get(ctx, pod.Name, metav1.GetOptions{}); err != nil {
+				return false, fmt.Errorf("failed to get pod: %w", err)
+			}
+
+			return pod.Status.Phase != corev1.PodRunning && pod.Status.Phase != corev1.PodPending, nil
+		}); err != nil {
+		return fmt.Errorf("failed to wait for tunnel pod %s/%s: %w", namespace, pod.Name, err)
+	}
+
+	if pod.Status.Phase == corev1.PodRunning {
+		return nil
+	}
+
+	reason := probeFailureReason(pod, relay.address())
+	if strings.Contains(reason, "remote port forwarding failed") {
+		return fmt.Errorf("%w: %s", errRelayPortInUse, reason)
+	}
+
+	return fmt.Errorf("%w: tunnel to the relay host failed: %s", ErrUnreachable, reason)
+}
+
+func parseRelayHost(relaySSH string) (*relayHost, error) {
+	user, hostPort, ok := strings.Cut(relaySSH, "@")
+	if !ok || user == "" || hostPort == "" {
+		return nil, fmt.Errorf("invalid relay ssh address, expected user@host[:port]: %s", relaySSH)
+	}
+
+	// the address ends up in shell commands, so only the characters of user names, host names and IPs are allowed
+	if !relayAddressRegex.MatchString(relaySSH) {
+		return nil, fmt.Errorf("relay ssh address contains invalid characters: %s", relaySSH)
+	}
+
+	host, portStr, err := net.SplitHostPort(hostPort)
+	if err != nil {
+		var addrErr *net.AddrError
+		if !errors.As(err, &addrErr) || addrErr.Err != "missing port in address" {
+			return nil, fmt.Errorf("invalid relay ssh address %s: %w", relaySSH, err)
+		}
+
+		return &relayHost{user: user, host: hostPort, port: sshdServicePort}, nil
+	}
+
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid port in relay ssh address %s: %w", relaySSH, err)
+	}
+
+	return &relayHost{user: user, host: host, port: port}, nil
+}
+
+func (h *relayHost) address() string {
+	return h.user + "@" + net.JoinHostPort(h.host, strconv.Itoa(h.port))
+}
+
+// sshArgs returns the arguments of ssh to connect to the bastion, without a host key check,
+// as there is no known_hosts file in the pods.
+func (h *relayHost) sshArgs(privateKeyPath string) []string {
+	return []string{
+		"ssh", "-o", "StrictHostKeyChecking=no", "-o", "UserKnownHostsFile=/dev/null",
+		"-o", "ConnectTimeout=" + strconv.Itoa(relayConnectTimeout),
+		"-o", "ServerAliveInterval=" + strconv.Itoa(relayKeepAliveSecs),
+		"-i", privateKeyPath, "-p", strconv.Itoa(h.port),
+	}
+}
+
+// buildRelayTunnelCmd builds the command which listens on the tunnel port on the bastion,
+// forwarding the connections to the sshd. It reconnects when the connection is lost, until the job is deleted.
+//
+// ssh goes into the background only after the port is forwarded, so the command fails if the first connection
+// cannot forward the port, e.g. as it is already in use. Otherwise, it logs relayTunnelEstablishedMsg and
+// checks the connection over the control socket of ssh.
+func buildRelayTunnelCmd(relay *relayHost, tunnelPort int, sshdHost, privateKeyPath string) string {
+	target := relay.user + "@" + relay.host
+	args := append(relay.sshArgs(privateKeyPath), "-o", "ExitOnForwardFailure=yes", "-f", "-N",
+		"-M", "-S", relayControlSocket, "-R", fmt.Sprintf("%d:%s:%d", tunnelPort, sshdHost, sshdServicePort), target)
+
+	return fmt.Sprintf(`connect() { rm -f %[1]s; %[2]s; }
+connect || exit 1
+echo "%[3]s"
+while :; do
+  while ssh -S %[1]s -O check %[4]s 2>/dev/null; do sleep %[5]d; done
+  echo "tunnel closed, reconnecting"
+  until connect; do sleep %[5]d; done
+done
+`, relayControlSocket, strings.Join(args, " "), relayTunnelEstablishedMsg, target, relayReconnectSecs)
+}
+
+// buildRelayProxyCmd builds the ssh proxy command which connects to the target over the bastion.
+func buildRelayProxyCmd(relay *relayHost, privateKeyPath string) string {
+	args := append(relay.sshArgs(privateKeyPath), "-W", "%h:%p", relay.user+"@"+relay.host)
+
+	return strings.Join(args, " ")
+}
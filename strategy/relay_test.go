@@ -0,0 +1,56 @@
+package strategy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRelayHost(t *testing.T) {
+	t.Parallel()
+
+	relay, err := parseRelayHost("jump@bastion.example.com")
+	require.NoError(t, err)
+	assert.Equal(t, &relayHost{user: "jump", host: "bastion.example.com", port: 22}, relay)
+
+	relay, err = parseRelayHost("jump@10.0.0.1:2222")
+	require.NoError(t, err)
+	assert.Equal(t, &relayHost{user: "jump", host: "10.0.0.1", port: 2222}, relay)
+
+	relay, err = parseRelayHost("jump@[2001:db8::1]:2222")
+	require.NoError(t, err)
+	assert.Equal(t, &relayHost{user: "jump", host: "2001:db8::1", port: 2222}, relay)
+	assert.Equal(t, "jump@[2001:db8::1]:2222", relay.address())
+
+	for _, invalid := range []string{
+		"bastion.example.com", "@bastion", "jump@", "jump@bastion:ssh",
+		"jump@bastion;reboot", "jump@$(reboot)", "jump@'bastion'",
+	} {
+		_, err = parseRelayHost(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestBuildRelayCmds(t *testing.T) {
+	t.Parallel()
+
+	relay := &relayHost{user: "jump", host: "bastion", port: 2222}
+
+	assert.Equal(t, `connect() { rm -f /tmp/relay.sock; ssh -o StrictHostKeyChecking=no `+
+		`-o UserKnownHostsFile=/dev/null -o ConnectTimeout=10 -o ServerAliveInterval=15 -i /tmp/id_relay -p 2222 `+
+		`-o ExitOnForwardFailure=yes -f -N -M -S /tmp/relay.sock -R 23456:pv-migrate-abc-src-sshd.ns1:22 `+
+		`jump@bastion; }
+connect || exit 1
+echo "tunnel established"
+while :; do
+  while ssh -S /tmp/relay.sock -O check jump@bastion 2>/dev/null; do sleep 5; done
+  echo "tunnel closed, reconnecting"
+  until connect; do sleep 5; done
+done
+`, buildRelayTunnelCmd(relay, 23456, "pv-migrate-abc-src-sshd.ns1", "/tmp/id_relay"))
+
+	assert.Equal(t, "ssh -o StrictHostKeyChecking=no -o UserKnownHostsFile=/dev/null -o ConnectTimeout=10 "+
+		"-o ServerAliveInterval=15 -i /tmp/id_relay -p 2222 -W %h:%p jump@bastion",
+		buildRelayProxyCmd(relay, "/tmp/id_relay"))
+}
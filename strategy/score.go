@@ -0,0 +1,112 @@
+package strategy
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/utkuozdemir/pv-migrate/k8s"
+	"github.com/utkuozdemir/pv-migrate/migration"
+)
+
+const (
+	// AutoStrategy is not a strategy itself, but ranks all strategies by their scores and tries them in that order.
+	AutoStrategy = "auto"
+
+	// UnscoredValue is the score of the strategies which cannot estimate their feasibility.
+	UnscoredValue = 30
+
+	mnt2ScoreValue                 = 100
+	svcScoreValue                  = 80
+	lbSvcScoreValue                = 60
+	lbSvcUnknownScoreValue         = 40
+	lbSvcUnavailableScoreValue     = 10
+	nodePortScoreValue             = 45
+	execScoreValue                 = 20
+	localScoreValue                = 15
+	hostPathScoreValue             = 70
+	relayScoreValue                = 35
+	sameClusterLoadBalancerPenalty = 10
+	networkPolicyPenalty           = 20
+)
+
+// Score is the estimated feasibility of a strategy for a migration, based on its expected speed and success.
+//
+// A value of 0 means that the strategy cannot handle the migration, higher values are better.
+type Score struct {
+	Value   int
+	Reasons []string
+}
+
+// Scorer is implemented by the strategies which can estimate their feasibility without running the migration.
+type Scorer interface {
+	Score(mig *migration.Migration, facts *Facts) Score
+}
+
+// Facts are the information about the clusters of a migration which the strategies are scored by.
+type Facts struct {
+	// LoadBalancerAvailable is nil if it could not be determined.
+	LoadBalancerAvailable *bool
+	SourceNetworkPolicies bool
+	DestNetworkPolicies   bool
+	// SourceNodeLocal is whether the source PV is a hostPath or local volume pinned to a node.
+	SourceNodeLocal bool
+}
+
+// GatherFacts queries the clusters of the migration for the facts to score the strategies by.
+//
+// The facts which cannot be determined, e.g. due to missing permissions, are left at their defaults.
+func GatherFacts(ctx context.Context, mig *migration.Migration, logger *slog.Logger) *Facts {
+	var facts Facts
+
+	sourceInfo := mig.SourceInfo
+	destInfo := mig.DestInfo
+
+	lbAvailable, err := k8s.HasAssignedLoadBalancer(ctx, sourceInfo.ClusterClient.KubeClient)
+	if err != nil {
+		logger.Debug("failed to determine if a load balancer is available", "error", err)
+	} else {
+		facts.LoadBalancerAvailable = &lbAvailable
+	}
+
+	facts.SourceNetworkPolicies, err = k8s.HasNetworkPolicies(ctx,
+		sourceInfo.ClusterClient.KubeClient, sourceInfo.Claim.Namespace)
+	if err != nil {
+		logger.Debug("failed to determine if there are network policies on the source", "error", err)
+	}
+
+	facts.DestNetworkPolicies, err = k8s.HasNetworkPolicies(ctx,
+		destInfo.ClusterClient.KubeClient, destInfo.Claim.Namespace)
+	if err != nil {
+		logger.Debug("failed to determine if there are network policies on the destination", "error", err)
+	}
+
+	srcVolume, err := getNodeLocalVolume(ctx, sourceInfo)
+	if err != nil {
+		logger.Debug("failed to determine if the source PV is node-local", "error", err)
+	}
+
+	facts.SourceNodeLocal = srcVolume != nil
+
+	return &facts
+}
+
+func sameCluster(mig *migration.Migration) bool {
+	return mig.SourceInfo.ClusterClient.RestConfig.Host == mig.DestInfo.ClusterClient.RestConfig.Host
+}
+
+// penalize lowers the value of a feasible score for the given reason, keeping it feasible.
+func penalize(score *Score, penalty int, reason string) {
+	score.Value = max(score.Value-penalty, 1)
+	score.Reasons = append(score.Reasons, reason)
+}
+
+// penalizeNetworkPolicies lowers the score of a strategy which sends the data over the network between pods,
+// if there are network policies which might block the traffic.
+func penalizeNetworkPolicies(score *Score, facts *Facts) {
+	if !facts.SourceNetworkPolicies && !facts.DestNetworkPolicies {
+		return
+	}
+
+	penalize(score, networkPolicyPenalty, "NetworkPolicies present, they might block the traffic unless "+
+		"sshd.networkPolicy.enabled and rsync.networkPolicy.enabled helm values are set")
+}
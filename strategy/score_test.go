@@ -0,0 +1,56 @@
+package strategy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/neilotoole/slogt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/utkuozdemir/pv-migrate/migration"
+	"github.com/utkuozdemir/pv-migrate/pvc"
+)
+
+func TestScoreSameCluster(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	pvcA := buildTestPVC("namespace1", "pvc1", corev1.ReadWriteOnce)
+	pvcB := buildTestPVC("namespace2", "pvc2", corev1.ReadWriteOnce)
+	podA := buildTestPod("namespace1", "pod1", "node1", "pvc1")
+	podB := buildTestPod("namespace2", "pod2", "node2", "pvc2")
+	policy := networkingv1.NetworkPolicy{ObjectMeta: metav1.ObjectMeta{Namespace: "namespace2", Name: "deny-all"}}
+	cli := buildTestClient(pvcA, pvcB, podA, podB, &policy)
+
+	src, err := pvc.New(ctx, cli, "namespace1", "pvc1")
+	require.NoError(t, err)
+
+	dst, err := pvc.New(ctx, cli, "namespace2", "pvc2")
+	require.NoError(t, err)
+
+	mig := migration.Migration{
+		Request:    &migration.Request{DeleteExtraneousFiles: true},
+		SourceInfo: src,
+		DestInfo:   dst,
+	}
+
+	facts := GatherFacts(ctx, &mig, slogt.New(t))
+	require.NotNil(t, facts.LoadBalancerAvailable)
+	assert.False(t, *facts.LoadBalancerAvailable)
+	assert.False(t, facts.SourceNetworkPolicies)
+	assert.True(t, facts.DestNetworkPolicies)
+
+	assert.Equal(t, 0, (&Mnt2{}).Score(&mig, facts).Value)
+	assert.Equal(t, svcScoreValue-networkPolicyPenalty, (&Svc{}).Score(&mig, facts).Value)
+	assert.Equal(t, 1, (&LbSvc{}).Score(&mig, facts).Value)
+	assert.Equal(t, 0, (&Exec{}).Score(&mig, facts).Value)
+
+	score := (&LbSvc{}).Score(&mig, facts)
+	assert.Len(t, score.Reasons, 3)
+}
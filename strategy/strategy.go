@@ -24,31 +24,51 @@ import (
 )
 
 const (
-	Mnt2Strategy  = "mnt2"
-	SvcStrategy   = "svc"
-	LbSvcStrategy = "lbsvc"
-	LocalStrategy = "local"
+	Mnt2Strategy     = "mnt2"
+	SvcStrategy      = "svc"
+	LbSvcStrategy    = "lbsvc"
+	LocalStrategy    = "local"
+	NodePortStrategy = "nodeport"
+	ExecStrategy     = "exec"
+	HostPathStrategy = "hostpath"
+	RelayStrategy    = "relay"
 
 	helmValuesYAMLIndent = 2
 
 	srcMountPath  = "/source"
 	destMountPath = "/dest"
+
+	// rootlessSSHUser and rootlessSSHDPort are the user the transfer logs in to the sshd as
+	// and the port the sshd listens on, when the pods run as a non-root user.
+	rootlessSSHUser  = "pv-migrate"
+	rootlessSSHDPort = 2222
 )
 
 var (
 	DefaultStrategies = []string{Mnt2Strategy, SvcStrategy, LbSvcStrategy}
-	AllStrategies     = []string{Mnt2Strategy, SvcStrategy, LbSvcStrategy, LocalStrategy}
+	AllStrategies     = []string{
+		Mnt2Strategy, SvcStrategy, LbSvcStrategy, LocalStrategy,
+		NodePortStrategy, ExecStrategy, HostPathStrategy, RelayStrategy,
+	}
 
 	nameToStrategy = map[string]Strategy{
-		Mnt2Strategy:  &Mnt2{},
-		SvcStrategy:   &Svc{},
-		LbSvcStrategy: &LbSvc{},
-		LocalStrategy: &Local{},
+		Mnt2Strategy:     &Mnt2{},
+		SvcStrategy:      &Svc{},
+		LbSvcStrategy:    &LbSvc{},
+		LocalStrategy:    &Local{},
+		NodePortStrategy: &NodePort{},
+		ExecStrategy:     &Exec{},
+		HostPathStrategy: &HostPath{},
+		RelayStrategy:    &Relay{},
 	}
 
 	helmProviders = getter.All(cli.New())
 
 	ErrUnaccepted = errors.New("unaccepted")
+
+	// ErrUnreachable is returned by the strategies which found out that the destination cannot reach the source
+	// over the network they use, before attempting the transfer.
+	ErrUnreachable = errors.New("unreachable")
 )
 
 type Strategy interface {
@@ -122,6 +142,10 @@ func cleanup(attempt *migration.Attempt, releaseNames []string, logger *slog.Log
 	var errs error
 
 	for _, info := range []*pvc.Info{mig.SourceInfo, mig.DestInfo} {
+		if info == nil { // export and import have a single PVC
+			continue
+		}
+
 		for _, name := range releaseNames {
 			err := cleanupForPVC(name, req.HelmTimeout, info, logger)
 			if err != nil {
@@ -190,9 +214,19 @@ func getMergedHelmValues(helmValuesFile string, request *migration.Request) (map
 	return mergedValues, nil
 }
 
-func installHelmChart(attempt *migration.Attempt, pvcInfo *pvc.Info, name string,
+func installHelmChart(ctx context.Context, attempt *migration.Attempt, pvcInfo *pvc.Info, name string,
 	values map[string]any, logger *slog.Logger,
 ) error {
+	if request := attempt.Migration.Request; request.Rootless {
+		values["rootless"] = map[string]any{
+			"enabled":  true,
+			"uid":      request.RootlessUID,
+			"user":     rootlessSSHUser,
+			"sshdPort": rootlessSSHDPort,
+			"fsGroup":  mountsDest(attempt.Migration, pvcInfo, values),
+		}
+	}
+
 	helmValuesFile, err := writeHelmValuesToTempFile(attempt.ID, values)
 	if err != nil {
 		return fmt.Errorf("failed to write helm values to temp file: %w", err)
@@ -214,10 +248,10 @@ func installHelmChart(attempt *migration.Attempt, pvcInfo *pvc.Info, name string
 	install.ReleaseName = name
 	install.Wait = true
 
-	if req := mig.Request; req.HelmTimeout < req.LBSvcTimeout {
-		install.Timeout = req.LBSvcTimeout
-	} else {
-		install.Timeout = req.HelmTimeout
+	install.Timeout = attempt.InstallTimeout
+
+	if install.Timeout == 0 {
+		install.Timeout = mig.Request.HelmTimeout
 	}
 
 	vals, err := getMergedHelmValues(helmValuesFile, mig.Request)
@@ -225,13 +259,38 @@ func installHelmChart(attempt *migration.Attempt, pvcInfo *pvc.Info, name string
 		return fmt.Errorf("failed to get merged helm values: %w", err)
 	}
 
-	if _, err = install.Run(mig.Chart, vals); err != nil {
+	if _, err = install.RunWithContext(ctx, mig.Chart, vals); err != nil {
 		return fmt.Errorf("failed to install helm chart: %w", err)
 	}
 
 	return nil
 }
 
+// mountsDest returns whether the pods of the release installed next to the given PVC mount the destination PVC.
+//
+// Only these pods get the fsGroup in rootless mode to be able to write to it, as the kubelet changes the group
+// of all the files of the volumes of the pod to the fsGroup.
+func mountsDest(mig *migration.Migration, pvcInfo *pvc.Info, values map[string]any) bool {
+	destInfo := mig.DestInfo
+	if destInfo == nil || pvcInfo.Claim.Namespace != destInfo.Claim.Namespace ||
+		pvcInfo.ClusterClient.RestConfig.Host != destInfo.ClusterClient.RestConfig.Host {
+		return false
+	}
+
+	for _, component := range []string{"sshd", "rsync"} {
+		componentValues, _ := values[component].(map[string]any)
+		mounts, _ := componentValues["pvcMounts"].([]map[string]any)
+
+		for _, mount := range mounts {
+			if mount["name"] == destInfo.Claim.Name {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
 func writeHelmValuesToTempFile(id string, vals map[string]any) (string, error) {
 	file, err := os.CreateTemp("", fmt.Sprintf("pv-migrate-vals-%s-*.yaml", id))
 	if err != nil {
@@ -250,3 +309,21 @@ func writeHelmValuesToTempFile(id string, vals map[string]any) (string, error) {
 
 	return file.Name(), nil
 }
+
+// sshUser returns the user the transfer logs in to the sshd of the migration as.
+func sshUser(request *migration.Request) string {
+	if request.Rootless {
+		return rootlessSSHUser
+	}
+
+	return "root"
+}
+
+// sshdPodPort returns the port the sshd of the migration listens on in its pod.
+func sshdPodPort(request *migration.Request) int {
+	if request.Rootless {
+		return rootlessSSHDPort
+	}
+
+	return localSSHPort
+}
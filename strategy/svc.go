@@ -5,9 +5,7 @@ import (
 	"fmt"
 	"log/slog"
 
-	"github.com/utkuozdemir/pv-migrate/k8s"
 	"github.com/utkuozdemir/pv-migrate/migration"
-	"github.com/utkuozdemir/pv-migrate/rsync"
 	"github.com/utkuozdemir/pv-migrate/ssh"
 )
 
@@ -21,107 +19,66 @@ func (r *Svc) canDo(t *migration.Migration) bool {
 	return sameCluster
 }
 
-func (r *Svc) Run(ctx context.Context, attempt *migration.Attempt, logger *slog.Logger) error {
-	mig := attempt.Migration
+func (r *Svc) Score(mig *migration.Migration, facts *Facts) Score {
 	if !r.canDo(mig) {
-		return ErrUnaccepted
-	}
-
-	releaseName := attempt.HelmReleaseNamePrefix
-	releaseNames := []string{releaseName}
-
-	helmVals, err := buildHelmVals(mig, releaseName, logger)
-	if err != nil {
-		return fmt.Errorf("failed to build helm values: %w", err)
+		return Score{Reasons: []string{"PVCs are in different clusters"}}
 	}
 
-	doneCh := registerCleanupHook(attempt, releaseNames, logger)
-	defer cleanupAndReleaseHook(ctx, attempt, releaseNames, doneCh, logger)
+	score := Score{Value: svcScoreValue, Reasons: []string{"PVCs are in the same cluster"}}
+	penalizeNetworkPolicies(&score, facts)
 
-	err = installHelmChart(attempt, mig.DestInfo, releaseName, helmVals, logger)
-	if err != nil {
-		return fmt.Errorf("failed to install helm chart: %w", err)
-	}
-
-	showProgressBar := !mig.Request.NoProgressBar
-	kubeClient := mig.SourceInfo.ClusterClient.KubeClient
-	jobName := releaseName + "-rsync"
+	return score
+}
 
-	if err = k8s.WaitForJobCompletion(ctx, kubeClient,
-		mig.DestInfo.Claim.Namespace, jobName, showProgressBar, logger); err != nil {
-		return fmt.Errorf("failed to wait for job completion: %w", err)
+func (r *Svc) Run(ctx context.Context, attempt *migration.Attempt, logger *slog.Logger) error {
+	mig := attempt.Migration
+	if !r.canDo(mig) {
+		return ErrUnaccepted
 	}
 
-	return nil
-}
-
-//nolint:funlen
-func buildHelmVals(mig *migration.Migration, helmReleaseName string, logger *slog.Logger) (map[string]any, error) {
 	sourceInfo := mig.SourceInfo
 	destInfo := mig.DestInfo
-	sourceNs := sourceInfo.Claim.Namespace
-	destNs := destInfo.Claim.Namespace
 	keyAlgorithm := mig.Request.KeyAlgorithm
 
 	logger.Info("🔑 Generating SSH key pair", "algorithm", keyAlgorithm)
 
 	publicKey, privateKey, err := ssh.CreateSSHKeyPair(keyAlgorithm)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create ssh key pair: %w", err)
+		return fmt.Errorf("failed to create ssh key pair: %w", err)
 	}
 
 	privateKeyMountPath := "/tmp/id_" + keyAlgorithm
 
-	sshTargetHost := helmReleaseName + "-sshd." + sourceNs
+	srcReleaseName := attempt.HelmReleaseNamePrefix + "-src"
+	destReleaseName := attempt.HelmReleaseNamePrefix + "-dest"
+	probeReleaseName := attempt.HelmReleaseNamePrefix + "-probe"
+	releaseNames := []string{srcReleaseName, probeReleaseName, destReleaseName}
+
+	doneCh := registerCleanupHook(attempt, releaseNames, logger)
+	defer cleanupAndReleaseHook(ctx, attempt, releaseNames, doneCh, logger)
+
+	err = installOnSource(ctx, attempt, srcReleaseName, publicKey, srcMountPath, "ClusterIP", logger)
+	if err != nil {
+		return fmt.Errorf("failed to install on source: %w", err)
+	}
+
+	sshTargetHost := srcReleaseName + "-sshd." + sourceInfo.Claim.Namespace
 	if mig.Request.DestHostOverride != "" {
 		sshTargetHost = mig.Request.DestHostOverride
 	}
 
-	srcPath := srcMountPath + "/" + mig.Request.Source.Path
-	destPath := destMountPath + "/" + mig.Request.Dest.Path
-	rsyncCmd := rsync.Cmd{
-		NoChown:    mig.Request.NoChown,
-		Delete:     mig.Request.DeleteExtraneousFiles,
-		SrcPath:    srcPath,
-		DestPath:   destPath,
-		SrcUseSSH:  true,
-		SrcSSHHost: sshTargetHost,
-		Compress:   mig.Request.Compress,
+	if err = probeSSHD(ctx, attempt, probeReleaseName, sshTargetHost, 0, logger); err != nil {
+		return err
 	}
 
-	rsyncCmdStr, err := rsyncCmd.Build()
+	err = installOnDest(ctx, attempt, destReleaseName, privateKey, privateKeyMountPath,
+		sshTargetHost, 0, srcMountPath, destMountPath, logger)
 	if err != nil {
-		return nil, fmt.Errorf("failed to build rsync command: %w", err)
+		return fmt.Errorf("failed to install on dest: %w", err)
 	}
 
-	return map[string]any{
-		"rsync": map[string]any{
-			"enabled":             true,
-			"namespace":           destNs,
-			"privateKeyMount":     true,
-			"privateKey":          privateKey,
-			"privateKeyMountPath": privateKeyMountPath,
-			"pvcMounts": []map[string]any{
-				{
-					"name":      destInfo.Claim.Name,
-					"mountPath": destMountPath,
-				},
-			},
-			"command":  rsyncCmdStr,
-			"affinity": destInfo.AffinityHelmValues,
-		},
-		"sshd": map[string]any{
-			"enabled":   true,
-			"namespace": sourceNs,
-			"publicKey": publicKey,
-			"pvcMounts": []map[string]any{
-				{
-					"name":      sourceInfo.Claim.Name,
-					"mountPath": srcMountPath,
-					"readOnly":  mig.Request.SourceMountReadOnly,
-				},
-			},
-			"affinity": sourceInfo.AffinityHelmValues,
-		},
-	}, nil
+	kubeClient := destInfo.ClusterClient.KubeClient
+	jobName := destReleaseName + "-rsync"
+
+	return waitForTransferJob(ctx, mig, kubeClient, destInfo.Claim.Namespace, jobName, logger)
 }
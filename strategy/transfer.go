@@ -0,0 +1,346 @@
+package strategy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"helm.sh/helm/v3/pkg/cli/values"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/utkuozdemir/pv-migrate/k8s"
+	"github.com/utkuozdemir/pv-migrate/migration"
+	"github.com/utkuozdemir/pv-migrate/rsync"
+	"github.com/utkuozdemir/pv-migrate/rsync/progress"
+	"github.com/utkuozdemir/pv-migrate/transfer"
+	"github.com/utkuozdemir/pv-migrate/util"
+)
+
+// TransferError is returned when the transfer command exits with an error, explained by the engine of the migration.
+type TransferError struct {
+	Err    error
+	Status transfer.ExitStatus
+}
+
+func (e *TransferError) Error() string {
+	msg := e.Err.Error() + " (" + e.Status.Reason + ")"
+	if e.Status.Hint != "" {
+		msg += ": " + e.Status.Hint
+	}
+
+	return msg
+}
+
+// Unwrap returns the error and the kind of the failure, so that it can be matched against the Err* errors
+// of the transfer package.
+func (e *TransferError) Unwrap() []error {
+	if e.Status.Err == nil {
+		return []error{e.Err}
+	}
+
+	return []error{e.Err, e.Status.Err}
+}
+
+const (
+	// filterRulesMountPath is where the filter rules of the migration are mounted into the rsync job.
+	filterRulesMountPath = "/tmp/filter-rules"
+	// proxyJumpKeyMountPath is where the key of the jump hosts is mounted into the rsync job.
+	proxyJumpKeyMountPath = "/tmp/id_proxy_jump"
+)
+
+// buildFilter returns the filter of the transfer command, reading the filter rules from where they are mounted.
+func buildFilter(request *migration.Request) rsync.Filter {
+	filter := rsync.Filter{Includes: request.Includes, Excludes: request.Excludes}
+	if request.FilterRules != "" {
+		filter.RulesFile = filterRulesMountPath
+	}
+
+	return filter
+}
+
+// buildTransferCmd builds the command which copies the data from src into dest with the engine of the migration.
+func buildTransferCmd(mig *migration.Migration, src, dest *transfer.Endpoint, sshIdentityFile string) (string, error) {
+	return buildTransferCmdWithProxy(mig, src, dest, sshIdentityFile, "")
+}
+
+// buildTransferCmdWithProxy is like buildTransferCmd, but connects to the remote endpoint through the given
+// ssh proxy command.
+func buildTransferCmdWithProxy(mig *migration.Migration, src, dest *transfer.Endpoint,
+	sshIdentityFile, sshProxyCommand string,
+) (string, error) {
+	engine, err := transfer.Get(mig.Request.Engine)
+	if err != nil {
+		return "", fmt.Errorf("failed to get transfer engine: %w", err)
+	}
+
+	src, dest = withSSHUser(mig.Request, src), withSSHUser(mig.Request, dest)
+
+	extraArgs, err := rsyncExtraArgs(mig.Request)
+	if err != nil {
+		return "", err
+	}
+
+	cmd, err := engine.BuildCommand(src, dest, &transfer.Options{
+		NoChown:            mig.Request.NoChown,
+		Delete:             mig.Request.DeleteExtraneousFiles,
+		Compress:           mig.Request.Compress,
+		SSHIdentityFile:    sshIdentityFile,
+		SSHProxyCommand:    sshProxyCommand,
+		SSHOptions:         mig.Request.SSHOptions,
+		Filter:             buildFilter(mig.Request),
+		BwLimit:            mig.Request.BwLimit,
+		Preserve:           mig.Request.Preserve,
+		LargeFile:          mig.Request.LargeFile,
+		Ownership:          mig.Request.Ownership,
+		Parallel:           mig.Request.Parallel,
+		AllowVanishedFiles: mig.Request.AllowVanishedFiles,
+		ItemizeChanges:     mig.Request.ChangesLogPath != "",
+		ExtraArgs:          extraArgs,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to build transfer command: %w", err)
+	}
+
+	return cmd, nil
+}
+
+// rsyncExtraArgs returns the rsync.extraArgs helm value given by the user, split by whitespace.
+// They are passed to rsync in the transfer command instead of being appended to the command script,
+// which can wrap the rsync invocation.
+func rsyncExtraArgs(request *migration.Request) ([]string, error) {
+	valsOptions := values.Options{
+		Values:       request.HelmValues,
+		ValueFiles:   request.HelmValuesFiles,
+		StringValues: request.HelmStringValues,
+		FileValues:   request.HelmFileValues,
+	}
+
+	vals, err := valsOptions.MergeValues(helmProviders)
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge helm values: %w", err)
+	}
+
+	rsyncVals, _ := vals["rsync"].(map[string]any)
+
+	extraArgs, ok := rsyncVals["extraArgs"]
+	if !ok || extraArgs == nil {
+		return nil, nil
+	}
+
+	extraArgsStr, ok := extraArgs.(string)
+	if !ok {
+		return nil, fmt.Errorf("rsync.extraArgs must be a string, got %T", extraArgs)
+	}
+
+	return strings.Fields(extraArgsStr), nil
+}
+
+// setSSHClientHelmValues sets the ssh client config of the transfer in the values of the rsync job,
+// mounting the key of the jump hosts next to the private key if there is one.
+func setSSHClientHelmValues(rsyncVals map[string]any, request *migration.Request, privateKeyMountPath string) error {
+	if request.SSHProxyJumpKeyPath == "" {
+		rsyncVals["sshConfig"] = request.SSHOptions.ClientConfig(privateKeyMountPath, "")
+
+		return nil
+	}
+
+	proxyKey, err := os.ReadFile(request.SSHProxyJumpKeyPath)
+	if err != nil {
+		return fmt.Errorf("failed to read ssh proxy jump key: %w", err)
+	}
+
+	rsyncVals["sshConfig"] = request.SSHOptions.ClientConfig(privateKeyMountPath, proxyJumpKeyMountPath)
+	rsyncVals["proxyKey"] = string(proxyKey)
+	rsyncVals["proxyKeyMountPath"] = proxyJumpKeyMountPath
+
+	return nil
+}
+
+// transferJobSuspended returns whether the transfer job is created suspended, as it is outside the window.
+func transferJobSuspended(request *migration.Request) bool {
+	return request.Window != nil && !request.Window.Contains(time.Now())
+}
+
+// withSSHUser returns the endpoint logging in as the ssh user of the migration if it is remote,
+// without modifying the given one.
+func withSSHUser(request *migration.Request, endpoint *transfer.Endpoint) *transfer.Endpoint {
+	if endpoint.SSHHost == "" || endpoint.SSHUser != "" {
+		return endpoint
+	}
+
+	withUser := *endpoint
+	withUser.SSHUser = sshUser(request)
+
+	return &withUser
+}
+
+// waitForTransferJob waits for the job running the transfer command to complete,
+// displaying its progress and explaining its exit code by the engine of the migration.
+//
+// The job runs the transfer command once. If it fails in a way which might succeed when retried,
+// the job is re-created with an exponential backoff, up to the retries of the migration.
+func waitForTransferJob(ctx context.Context, mig *migration.Migration, cli kubernetes.Interface,
+	namespace, jobName string, logger *slog.Logger,
+) error {
+	retries := mig.Request.RsyncRetries
+
+	for retry := 1; ; retry++ {
+		err := waitForTransferJobInWindow(ctx, mig, cli, namespace, jobName, logger)
+		if err == nil || retry > retries || ctx.Err() != nil {
+			return err
+		}
+
+		reason, retryable := transferRetryReason(err)
+		if !retryable {
+			return err
+		}
+
+		backoff := util.Backoff(mig.Request.RsyncRetryBackoff, retry)
+
+		logger.Warn("🔁 Retrying the transfer", "retry", fmt.Sprintf("%d/%d", retry, retries),
+			"reason", reason, "backoff", backoff)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err() //nolint:wrapcheck
+		case <-time.After(backoff):
+		}
+
+		if err = k8s.RecreateJob(ctx, cli, namespace, jobName, transferJobSuspended(mig.Request)); err != nil {
+			return fmt.Errorf("failed to recreate transfer job: %w", err)
+		}
+	}
+}
+
+// transferRetryReason returns why the transfer job failed, and whether it might succeed when retried.
+//
+// The failures of the transfer command are retried if the engine considers them transient,
+// and the pods which failed without an exit code, e.g. evicted ones, are always retried.
+func transferRetryReason(err error) (string, bool) {
+	var transferErr *TransferError
+	if errors.As(err, &transferErr) {
+		return transferErr.Status.Reason, transferErr.Status.Retryable
+	}
+
+	var jobErr *k8s.JobFailedError
+	if errors.As(err, &jobErr) {
+		return "the pod failed without an exit code", true
+	}
+
+	return "", false
+}
+
+// waitForTransferJobInWindow waits for the job running the transfer command to complete.
+//
+// If the migration has a window, the job is suspended while the window is closed and resumed when it opens again.
+func waitForTransferJobInWindow(ctx context.Context, mig *migration.Migration, cli kubernetes.Interface,
+	namespace, jobName string, logger *slog.Logger,
+) error {
+	window := mig.Request.Window
+	if window == nil {
+		return waitForTransferJobCompletion(ctx, mig, cli, namespace, jobName, logger)
+	}
+
+	for {
+		now := time.Now()
+
+		if !window.Contains(now) {
+			if err := pauseTransferJob(ctx, mig, cli, namespace, jobName, window.NextStart(now), logger); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		// the job is created suspended outside the window, so it is resumed even if it was not paused here
+		if err := k8s.SuspendJob(ctx, cli, namespace, jobName, false); err != nil {
+			return fmt.Errorf("failed to resume transfer job: %w", err)
+		}
+
+		windowCtx, cancel := context.WithDeadline(ctx, window.NextEnd(now))
+		err := waitForTransferJobCompletion(windowCtx, mig, cli, namespace, jobName, logger)
+		windowClosed := errors.Is(windowCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil
+
+		cancel()
+
+		if err == nil || !windowClosed {
+			return err
+		}
+	}
+}
+
+// pauseTransferJob suspends the transfer job, killing the transfer command, and waits until the given time.
+// The job is resumed when the window is open again.
+func pauseTransferJob(ctx context.Context, mig *migration.Migration, cli kubernetes.Interface,
+	namespace, jobName string, resumeAt time.Time, logger *slog.Logger,
+) error {
+	logger.Info("⏸️ Pausing the transfer outside the window", "window", mig.Request.Window,
+		"resume_at", resumeAt.Format(time.DateTime))
+
+	if err := k8s.SuspendJob(ctx, cli, namespace, jobName, true); err != nil {
+		return fmt.Errorf("failed to suspend transfer job: %w", err)
+	}
+
+	if err := k8s.WaitForJobPodsDeletion(ctx, cli, namespace, jobName); err != nil {
+		return fmt.Errorf("failed to stop transfer job: %w", err)
+	}
+
+	if !mig.Request.NoProgressBar && ctx.Value(progress.CanDisplayProgressBarContextKey{}) != nil {
+		bar := progress.NewBar(-1, "⏸️ Paused until "+resumeAt.Format("15:04"))
+		defer func() { _ = bar.Finish() }()
+	}
+
+	timer := time.NewTimer(time.Until(resumeAt))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err() //nolint:wrapcheck
+	case <-timer.C:
+	}
+
+	logger.Info("▶️ Resuming the transfer")
+
+	return nil
+}
+
+func waitForTransferJobCompletion(ctx context.Context, mig *migration.Migration, cli kubernetes.Interface,
+	namespace, jobName string, logger *slog.Logger,
+) error {
+	engine, err := transfer.Get(mig.Request.Engine)
+	if err != nil {
+		return fmt.Errorf("failed to get transfer engine: %w", err)
+	}
+
+	changeFunc, closeChangesLog, err := openChangesLog(mig.Request)
+	if err != nil {
+		return err
+	}
+
+	defer closeChangesLog(logger)
+
+	err = k8s.WaitForJobCompletion(ctx, cli, namespace, jobName, progress.LoggerOptions{
+		ShowProgressBar: !mig.Request.NoProgressBar,
+		ParseLineFunc:   transfer.ParseProgressFunc(engine, mig.Request.Parallel),
+		ChangeFunc:      changeFunc,
+	}, logger)
+	if err == nil {
+		return nil
+	}
+
+	var jobErr *k8s.JobFailedError
+	if errors.As(err, &jobErr) && jobErr.ExitCode > 0 {
+		if jobErr.Message != "" {
+			logger.Warn("🔶 Transfer failed", "exit_code", jobErr.ExitCode, "output", jobErr.Message)
+		}
+
+		return fmt.Errorf("failed to wait for job completion: %w",
+			&TransferError{Err: err, Status: engine.ClassifyExitCode(jobErr.ExitCode, jobErr.Message)})
+	}
+
+	return fmt.Errorf("failed to wait for job completion: %w", err)
+}
@@ -0,0 +1,200 @@
+package strategy
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/neilotoole/slogt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/utkuozdemir/pv-migrate/k8s"
+	"github.com/utkuozdemir/pv-migrate/migration"
+	"github.com/utkuozdemir/pv-migrate/transfer"
+)
+
+func TestPauseTransferJob(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	job := batchv1.Job{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "rel-rsync"}}
+	cli := fake.NewSimpleClientset(&job)
+
+	mig := migration.Migration{Request: &migration.Request{
+		NoProgressBar: true,
+		Window:        &migration.Window{Start: 22 * time.Hour, End: 6 * time.Hour},
+	}}
+
+	require.NoError(t, pauseTransferJob(ctx, &mig, cli, "ns", "rel-rsync", time.Now(), slogt.New(t)))
+
+	var patches []string
+
+	for _, action := range cli.Actions() {
+		if patch, ok := action.(k8stesting.PatchAction); ok {
+			patches = append(patches, string(patch.GetPatch()))
+		}
+	}
+
+	assert.Equal(t, []string{`{"spec":{"suspend":true}}`}, patches)
+
+	canceledCtx, cancelNow := context.WithCancel(ctx)
+	cancelNow()
+
+	err := pauseTransferJob(canceledCtx, &mig, cli, "ns", "rel-rsync", time.Now().Add(time.Hour), slogt.New(t))
+	require.Error(t, err)
+}
+
+func TestTransferError(t *testing.T) {
+	t.Parallel()
+
+	jobErr := &k8s.JobFailedError{Namespace: "ns", Name: "rel-rsync-abc", ExitCode: 255}
+	err := fmt.Errorf("failed to wait for job completion: %w", &TransferError{
+		Err:    jobErr,
+		Status: (&transfer.Rsync{}).ClassifyExitCode(255, "ssh: connect to host x port 22: Connection refused"),
+	})
+
+	require.ErrorIs(t, err, transfer.ErrSSHConnection)
+	require.ErrorAs(t, err, &jobErr)
+	assert.Contains(t, err.Error(), "job ns/rel-rsync-abc failed with exit code 255 (ssh connection failed): "+
+		"SSH connection refused: ")
+}
+
+// newTransferJobClientset returns a clientset with a transfer job, which runs a new pod exiting with the next
+// of the given exit codes each time the job is created, as the job controller would.
+func newTransferJobClientset(t *testing.T, exitCodes ...int32) *fake.Clientset {
+	t.Helper()
+
+	job := batchv1.Job{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "rel-rsync"}}
+	cli := fake.NewSimpleClientset(&job)
+	runs := 0
+
+	runPod := func() error {
+		exitCode := exitCodes[runs]
+		runs++
+
+		phase := corev1.PodSucceeded
+		if exitCode != 0 {
+			phase = corev1.PodFailed
+		}
+
+		pod := corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "ns", Name: fmt.Sprintf("rel-rsync-%d", runs),
+				Labels: map[string]string{"job-name": "rel-rsync"},
+			},
+			Status: corev1.PodStatus{Phase: phase, ContainerStatuses: []corev1.ContainerStatus{{
+				State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: exitCode}},
+			}}},
+		}
+
+		return cli.Tracker().Add(&pod) //nolint:wrapcheck
+	}
+
+	require.NoError(t, runPod())
+
+	cli.PrependReactor("create", "jobs", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return false, nil, runPod()
+	})
+
+	cli.PrependReactor("delete", "jobs", func(k8stesting.Action) (bool, runtime.Object, error) {
+		podResource := corev1.SchemeGroupVersion.WithResource("pods")
+
+		return false, nil, cli.Tracker().Delete(podResource, "ns", fmt.Sprintf("rel-rsync-%d", runs)) //nolint:wrapcheck
+	})
+
+	return cli
+}
+
+func TestWaitForTransferJobRetries(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	mig := migration.Migration{Request: &migration.Request{
+		NoProgressBar:     true,
+		RsyncRetries:      2,
+		RsyncRetryBackoff: time.Millisecond,
+	}}
+
+	countCreations := func(cli *fake.Clientset) int {
+		creations := 0
+
+		for _, action := range cli.Actions() {
+			if action.Matches("create", "jobs") {
+				creations++
+			}
+		}
+
+		return creations
+	}
+
+	// a dropped connection is retried
+	cli := newTransferJobClientset(t, 12, 0)
+	require.NoError(t, waitForTransferJob(ctx, &mig, cli, "ns", "rel-rsync", slogt.New(t)))
+	assert.Equal(t, 1, countCreations(cli))
+
+	// up to the retries
+	cli = newTransferJobClientset(t, 12, 255, 12)
+	err := waitForTransferJob(ctx, &mig, cli, "ns", "rel-rsync", slogt.New(t))
+
+	var transferErr *TransferError
+	require.ErrorAs(t, err, &transferErr)
+	assert.Equal(t, "error in rsync protocol data stream", transferErr.Status.Reason)
+	assert.Equal(t, 2, countCreations(cli))
+
+	// a syntax error is not
+	cli = newTransferJobClientset(t, 1)
+	require.ErrorAs(t, waitForTransferJob(ctx, &mig, cli, "ns", "rel-rsync", slogt.New(t)), &transferErr)
+	assert.Equal(t, 0, countCreations(cli))
+}
+
+func TestBuildTransferCmdRootless(t *testing.T) {
+	t.Parallel()
+
+	mig := migration.Migration{Request: &migration.Request{NoChown: true, Rootless: true}}
+
+	src := transfer.Endpoint{Path: "/source/", SSHHost: "sshd.ns"}
+	dest := transfer.Endpoint{Path: "/dest/"}
+
+	cmd, err := buildTransferCmd(&mig, &src, &dest, "/tmp/id_ed25519")
+	require.NoError(t, err)
+	assert.Contains(t, cmd, " pv-migrate@sshd.ns:/source/ /dest/")
+	assert.Empty(t, src.SSHUser)
+
+	mig.Request.Rootless = false
+
+	cmd, err = buildTransferCmd(&mig, &src, &dest, "/tmp/id_ed25519")
+	require.NoError(t, err)
+	assert.Contains(t, cmd, " root@sshd.ns:/source/ /dest/")
+}
+
+func TestBuildTransferCmdExtraArgs(t *testing.T) {
+	t.Parallel()
+
+	mig := migration.Migration{Request: &migration.Request{
+		HelmValues: []string{"rsync.extraArgs=--partial  --inplace"},
+	}}
+
+	src := transfer.Endpoint{Path: "/source/"}
+	dest := transfer.Endpoint{Path: "/dest/"}
+
+	cmd, err := buildTransferCmd(&mig, &src, &dest, "")
+	require.NoError(t, err)
+	assert.Contains(t, cmd, " --partial --inplace /source/ /dest/")
+
+	mig.Request.Engine = "tar"
+
+	_, err = buildTransferCmd(&mig, &src, &dest, "")
+	require.ErrorContains(t, err, "the tar engine does not support the extra arguments of rsync")
+}
@@ -0,0 +1,217 @@
+package transfer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/utkuozdemir/pv-migrate/rsync"
+	"github.com/utkuozdemir/pv-migrate/rsync/progress"
+)
+
+const percentHundred = 100
+
+// Rclone runs rclone, reaching the remote endpoint over its on-the-fly sftp backend.
+//
+// It does not preserve the ownership of the files.
+type Rclone struct{}
+
+var rcloneExitCodeReasons = map[int]string{
+	1: "syntax or usage error",
+	2: "error not otherwise categorised",
+	3: "directory not found",
+	4: "file not found",
+	5: "temporary error",
+	6: "less serious errors",
+	7: "fatal error",
+	8: "transfer limit exceeded",
+	9: "no files transferred",
+}
+
+const rcloneTemporaryErrorExitCode = 5
+
+type rcloneLogLine struct {
+	Stats *struct {
+		Bytes      int64 `json:"bytes"`
+		TotalBytes int64 `json:"totalBytes"`
+	} `json:"stats"`
+}
+
+func (r *Rclone) BuildCommand(src, dest *Endpoint, opts *Options) (string, error) {
+	if src.remote() && dest.remote() {
+		return "", errors.New("cannot use sftp on both source and destination")
+	}
+
+	if opts.SSHProxyCommand != "" {
+		return "", errors.New("the rclone engine cannot connect through an ssh proxy command")
+	}
+
+	if !opts.Preserve.Empty() {
+		return "", errors.New("the rclone engine cannot preserve " + strings.Join(opts.Preserve.Options(), ","))
+	}
+
+	if !opts.LargeFile.Empty() {
+		return "", errors.New("the rclone engine does not support the large file modes")
+	}
+
+	if !opts.Ownership.Empty() {
+		return "", errors.New("the rclone engine cannot change the owners of the files")
+	}
+
+	if opts.AllowVanishedFiles {
+		return "", errors.New("the rclone engine cannot ignore the vanished files")
+	}
+
+	if opts.ItemizeChanges {
+		return "", errors.New("the rclone engine cannot itemize the changes of the files")
+	}
+
+	if len(opts.ExtraArgs) > 0 {
+		return "", errors.New("the rclone engine does not support the extra arguments of rsync")
+	}
+
+	sshOpts := opts.SSHOptions
+	if len(sshOpts.ProxyJump) > 0 || len(sshOpts.ExtraOptions) > 0 || sshOpts.ServerAliveInterval > 0 {
+		return "", errors.New("the rclone engine supports only the ciphers and the connect timeout of the ssh options")
+	}
+
+	if err := sshOpts.Validate(); err != nil {
+		return "", fmt.Errorf("invalid ssh options: %w", err)
+	}
+
+	if err := validateExcludesOnly(&opts.Filter, "rclone"); err != nil {
+		return "", err
+	}
+
+	subcommand := "copy"
+	if opts.Delete {
+		subcommand = "sync"
+	}
+
+	args := []string{
+		"rclone", subcommand, rclonePath(src), rclonePath(dest),
+		"--use-json-log", "--stats", "1s", "--stats-log-level", "NOTICE",
+	}
+
+	for _, pattern := range rcloneExcludes(opts.Filter.Excludes) {
+		args = append(args, "--exclude", shellQuote(pattern))
+	}
+
+	if opts.BwLimit != "" {
+		if err := rsync.ValidateBwLimit(opts.BwLimit); err != nil {
+			return "", fmt.Errorf("invalid bandwidth limit: %w", err)
+		}
+
+		args = append(args, "--bwlimit", opts.BwLimit)
+	}
+
+	if opts.Parallel > 1 {
+		args = append(args, "--transfers", strconv.Itoa(opts.Parallel))
+	}
+
+	remote := src
+	if dest.remote() {
+		remote = dest
+	}
+
+	if remote.remote() {
+		port := remote.SSHPort
+		if port == 0 {
+			port = defaultSSHPort
+		}
+
+		args = append(args, "--sftp-host", remote.SSHHost, "--sftp-port", strconv.Itoa(port),
+			"--sftp-user", remote.user())
+
+		if opts.SSHIdentityFile != "" {
+			args = append(args, "--sftp-key-file", opts.SSHIdentityFile)
+		}
+
+		if len(sshOpts.Ciphers) > 0 {
+			args = append(args, "--sftp-ciphers", strings.Join(sshOpts.Ciphers, ","))
+		}
+
+		if sshOpts.ConnectTimeout > 0 {
+			args = append(args, "--contimeout", sshOpts.ConnectTimeout.String())
+		}
+	}
+
+	return strings.Join(args, " "), nil
+}
+
+func (r *Rclone) ParseProgress(line string) (progress.Progress, error) {
+	var logLine rcloneLogLine
+
+	if err := json.Unmarshal([]byte(line), &logLine); err != nil {
+		return progress.Progress{}, fmt.Errorf("failed to parse rclone log line: %w", err)
+	}
+
+	if logLine.Stats == nil {
+		return progress.Progress{}, errors.New("no match")
+	}
+
+	transferred := logLine.Stats.Bytes
+	total := logLine.Stats.TotalBytes
+
+	percentage := 0
+	if total > 0 { // the total is not known until the listing of the source is complete
+		percentage = int(transferred * percentHundred / total)
+	}
+
+	return progress.Progress{
+		Line:        line,
+		Percentage:  percentage,
+		Transferred: transferred,
+		Total:       total,
+	}, nil
+}
+
+func (r *Rclone) ClassifyExitCode(code int, _ string) ExitStatus {
+	if code == 0 {
+		return ExitStatus{Success: true}
+	}
+
+	reason, ok := rcloneExitCodeReasons[code]
+	if !ok {
+		reason = "unknown error"
+	}
+
+	return ExitStatus{Retryable: code == rcloneTemporaryErrorExitCode, Reason: reason}
+}
+
+func rclonePath(endpoint *Endpoint) string {
+	if endpoint.remote() {
+		return shellQuote(":sftp:" + endpoint.Path)
+	}
+
+	return shellQuote(endpoint.Path)
+}
+
+// rcloneExcludes translates the rsync exclude patterns into the ones of rclone.
+//
+// A pattern without a slash at the end matches files and directories in rsync, and excludes the contents of the
+// directories with them, but it only matches the entry itself in rclone, so their contents are excluded explicitly.
+// The patterns ending with a slash or with /*** only match the directory and its contents in rsync.
+func rcloneExcludes(excludes []string) []string {
+	patterns := make([]string, 0, 2*len(excludes)) //nolint:mnd
+
+	for _, pattern := range excludes {
+		dir, dirOnly := strings.CutSuffix(pattern, "/***")
+		if !dirOnly {
+			dir, dirOnly = strings.CutSuffix(pattern, "/")
+		}
+
+		switch {
+		case dirOnly && dir != "":
+			patterns = append(patterns, dir+"/**")
+		case strings.HasSuffix(pattern, "**"):
+			patterns = append(patterns, pattern)
+		default:
+			patterns = append(patterns, pattern, pattern+"/**")
+		}
+	}
+
+	return patterns
+}
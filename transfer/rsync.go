@@ -0,0 +1,125 @@
+package transfer
+
+import (
+	"github.com/utkuozdemir/pv-migrate/rsync"
+	"github.com/utkuozdemir/pv-migrate/rsync/progress"
+)
+
+// Rsync is the default engine, which runs rsync over ssh.
+type Rsync struct{}
+
+const (
+	rsyncProtocolExitCode = 12
+	rsyncPartialExitCode  = 23
+	rsyncVanishedExitCode = 24
+)
+
+var rsyncExitCodeReasons = map[int]string{
+	1:   "syntax or usage error",
+	2:   "protocol incompatibility",
+	3:   "errors selecting input/output files or directories",
+	4:   "requested action not supported",
+	5:   "error starting client-server protocol",
+	6:   "daemon unable to append to log file",
+	10:  "error in socket I/O",
+	11:  "error in file I/O",
+	12:  "error in rsync protocol data stream",
+	13:  "errors with program diagnostics",
+	14:  "error in IPC code",
+	20:  "received SIGUSR1 or SIGINT",
+	21:  "some error returned by waitpid()",
+	22:  "error allocating core memory buffers",
+	23:  "partial transfer due to error",
+	24:  "partial transfer due to vanished source files",
+	25:  "the --max-delete limit stopped deletions",
+	30:  "timeout in data send/receive",
+	35:  "timeout waiting for daemon connection",
+	255: "ssh connection failed",
+}
+
+// rsyncRetryableExitCodes are the exit codes of rsync which might succeed when retried. 23 is not one of them,
+// as the files which could not be transferred usually fail again, e.g. for a permission error.
+var rsyncRetryableExitCodes = map[int]struct{}{
+	5:   {},
+	10:  {},
+	12:  {},
+	20:  {},
+	24:  {},
+	30:  {},
+	35:  {},
+	255: {},
+}
+
+func (r *Rsync) BuildCommand(src, dest *Endpoint, opts *Options) (string, error) {
+	cmd := rsync.Cmd{
+		NoChown:         opts.NoChown,
+		Delete:          opts.Delete,
+		SrcUseSSH:       src.remote(),
+		DestUseSSH:      dest.remote(),
+		SrcSSHUser:      src.SSHUser,
+		SrcSSHHost:      src.SSHHost,
+		SrcPath:         src.Path,
+		DestSSHUser:     dest.SSHUser,
+		DestSSHHost:     dest.SSHHost,
+		DestPath:        dest.Path,
+		Compress:        opts.Compress,
+		SSHIdentityFile: opts.SSHIdentityFile,
+		SSHProxyCommand: opts.SSHProxyCommand,
+		SSHOptions:      opts.SSHOptions,
+		Filter:          opts.Filter,
+		Preserve:        opts.Preserve,
+		LargeFile:       opts.LargeFile,
+		Ownership:       opts.Ownership,
+		BwLimit:         opts.BwLimit,
+		Parallel:        opts.Parallel,
+		AllowVanished:   opts.AllowVanishedFiles,
+		ItemizeChanges:  opts.ItemizeChanges,
+		ExtraArgs:       opts.ExtraArgs,
+	}
+
+	if src.remote() {
+		cmd.Port = src.SSHPort
+	} else {
+		cmd.Port = dest.SSHPort
+	}
+
+	return cmd.Build()
+}
+
+func (r *Rsync) ParseProgress(line string) (progress.Progress, error) {
+	return progress.ParseLine(line)
+}
+
+func (r *Rsync) ClassifyExitCode(code int, output string) ExitStatus {
+	if code == 0 {
+		return ExitStatus{Success: true}
+	}
+
+	reason, ok := rsyncExitCodeReasons[code]
+	if !ok {
+		reason = "unknown error"
+	}
+
+	_, retryable := rsyncRetryableExitCodes[code]
+	status := ExitStatus{Retryable: retryable, Reason: reason}
+
+	switch code {
+	case rsyncProtocolExitCode:
+		status.Err = ErrProtocol
+		status.Hint = "the connection was closed in the middle of the transfer: check the output, " +
+			"and whether the sshd pod on the other side was restarted, e.g. for running out of memory"
+	case rsyncPartialExitCode:
+		status.Err = ErrPartialTransfer
+		status.Hint = "some files could not be transferred: check the errors in the output, " +
+			"and pass --no-chown if the destination does not allow changing the owners of the files"
+	case rsyncVanishedExitCode:
+		status.Err = ErrVanishedFiles
+		status.Hint = "some source files were deleted during the transfer as the source is in use: " +
+			"stop the workloads using it, or pass --allow-vanished-files to ignore them"
+	case sshExitCode:
+		status.Err = ErrSSHConnection
+		status.Hint = sshFailureHint(output)
+	}
+
+	return status
+}
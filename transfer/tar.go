@@ -0,0 +1,185 @@
+package transfer
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/utkuozdemir/pv-migrate/rsync"
+	"github.com/utkuozdemir/pv-migrate/rsync/progress"
+)
+
+const sshExitCode = 255
+
+var errNoProgress = errors.New("tar does not report progress")
+
+// Tar pipes a tar stream from the source into the destination, over ssh if one of them is remote.
+//
+// It cannot delete extraneous files and does not report progress, but needs nothing but tar on both sides.
+type Tar struct{}
+
+func (t *Tar) BuildCommand(src, dest *Endpoint, opts *Options) (string, error) {
+	if src.remote() && dest.remote() {
+		return "", errors.New("cannot use ssh on both source and destination")
+	}
+
+	if opts.Delete {
+		return "", errors.New("the tar engine cannot delete extraneous files on the destination")
+	}
+
+	if !opts.Preserve.Empty() {
+		return "", errors.New("the tar engine cannot preserve " + strings.Join(opts.Preserve.Options(), ","))
+	}
+
+	if !opts.LargeFile.Empty() {
+		return "", errors.New("the tar engine does not support the large file modes")
+	}
+
+	if !opts.Ownership.Empty() {
+		return "", errors.New("the tar engine cannot change the owners of the files")
+	}
+
+	if opts.Parallel > 1 {
+		return "", errors.New("the tar engine cannot transfer in parallel")
+	}
+
+	if opts.AllowVanishedFiles {
+		return "", errors.New("the tar engine cannot ignore the vanished files")
+	}
+
+	if opts.ItemizeChanges {
+		return "", errors.New("the tar engine cannot itemize the changes of the files")
+	}
+
+	if len(opts.ExtraArgs) > 0 {
+		return "", errors.New("the tar engine does not support the extra arguments of rsync")
+	}
+
+	if opts.BwLimit != "" {
+		return "", errors.New("the tar engine cannot limit the bandwidth")
+	}
+
+	if err := opts.SSHOptions.Validate(); err != nil {
+		return "", fmt.Errorf("invalid ssh options: %w", err)
+	}
+
+	if err := validateExcludesOnly(&opts.Filter, "tar"); err != nil {
+		return "", err
+	}
+
+	if opts.SSHProxyCommand != "" && len(opts.SSHOptions.ProxyJump) > 0 {
+		return "", errors.New("ssh proxy jump cannot be used together with a proxy command")
+	}
+
+	tarFlags := "-f -"
+	if opts.Compress && (src.remote() || dest.remote()) {
+		tarFlags = "-z -f -"
+	}
+
+	create := "tar -c " + tarFlags
+
+	for _, arg := range TarExcludeArgs(opts.Filter.Excludes) {
+		create += " " + shellQuote(arg)
+	}
+
+	create += " -C " + shellQuote(src.Path) + " ."
+	extract := "mkdir -p " + shellQuote(dest.Path) + " && tar -x " + tarFlags + " -C " + shellQuote(dest.Path)
+
+	if opts.NoChown {
+		extract += " -o"
+	}
+
+	if src.remote() {
+		create = sshCmd(src, opts) + " " + shellQuote(create)
+	}
+
+	if dest.remote() {
+		extract = sshCmd(dest, opts) + " " + shellQuote(extract)
+	} else {
+		extract = "(" + extract + ")"
+	}
+
+	return fmt.Sprintf("(set -o pipefail && %s | %s)", create, extract), nil
+}
+
+func (t *Tar) ParseProgress(string) (progress.Progress, error) {
+	return progress.Progress{}, errNoProgress
+}
+
+func (t *Tar) ClassifyExitCode(code int, output string) ExitStatus {
+	switch code {
+	case 0:
+		return ExitStatus{Success: true}
+	case sshExitCode:
+		return ExitStatus{
+			Retryable: true, Reason: "ssh connection failed",
+			Err: ErrSSHConnection, Hint: sshFailureHint(output),
+		}
+	default:
+		return ExitStatus{Reason: "tar failed"}
+	}
+}
+
+func sshCmd(endpoint *Endpoint, opts *Options) string {
+	port := endpoint.SSHPort
+	if port == 0 {
+		port = defaultSSHPort
+	}
+
+	args := append([]string{"ssh"}, opts.SSHOptions.Args()...)
+	args = append(args, "-p", strconv.Itoa(port))
+
+	if opts.SSHIdentityFile != "" {
+		args = append(args, "-i", opts.SSHIdentityFile)
+	}
+
+	if opts.SSHProxyCommand != "" {
+		args = append(args, "-o", shellQuote("ProxyCommand="+opts.SSHProxyCommand))
+	}
+
+	args = append(args, endpoint.user()+"@"+endpoint.SSHHost)
+
+	return strings.Join(args, " ")
+}
+
+// TarExcludeArgs returns the --exclude arguments of tar, and of du, for the exclude patterns in the syntax of rsync.
+//
+// tar sees the paths as ./dir/file, so the / anchoring a pattern at the transferred directory is replaced with ./.
+// The trailing / of a pattern is dropped, as tar cannot match only the directories, so it excludes the files
+// of that name as well.
+func TarExcludeArgs(excludes []string) []string {
+	args := make([]string, 0, len(excludes))
+
+	for _, pattern := range excludes {
+		if trimmed := strings.TrimRight(pattern, "/"); trimmed != "" {
+			pattern = trimmed
+		}
+
+		if strings.HasPrefix(pattern, "/") {
+			pattern = "." + pattern
+		}
+
+		args = append(args, "--exclude="+pattern)
+	}
+
+	return args
+}
+
+// validateExcludesOnly returns an error if the filter has rules other than excludes, which the engine cannot apply.
+func validateExcludesOnly(filter *rsync.Filter, engine string) error {
+	if len(filter.Includes) > 0 || filter.RulesFile != "" {
+		return fmt.Errorf("the %s engine supports only excludes in the filter rules", engine)
+	}
+
+	if err := filter.Validate(); err != nil {
+		return fmt.Errorf("invalid filter: %w", err)
+	}
+
+	return nil
+}
+
+// shellQuote quotes the string to be used as a single word in a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
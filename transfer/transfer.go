@@ -0,0 +1,158 @@
+package transfer
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/utkuozdemir/pv-migrate/rsync"
+	"github.com/utkuozdemir/pv-migrate/rsync/progress"
+)
+
+const (
+	RsyncEngine  = "rsync"
+	TarEngine    = "tar"
+	RcloneEngine = "rclone"
+
+	DefaultEngine = RsyncEngine
+
+	defaultSSHUser = "root"
+	defaultSSHPort = 22
+)
+
+var (
+	// ErrProtocol is the kind of the failures due to the connection being closed in the middle of the transfer.
+	ErrProtocol = errors.New("protocol data stream broke")
+	// ErrPartialTransfer is the kind of the failures where some files could not be transferred.
+	ErrPartialTransfer = errors.New("partial transfer")
+	// ErrVanishedFiles is the kind of the failures where some source files were deleted during the transfer.
+	ErrVanishedFiles = errors.New("source files vanished")
+	// ErrSSHConnection is the kind of the failures to connect to the remote endpoint over ssh.
+	ErrSSHConnection = errors.New("ssh connection failed")
+)
+
+var (
+	Engines = []string{RsyncEngine, TarEngine, RcloneEngine}
+
+	nameToEngine = map[string]Engine{
+		RsyncEngine:  &Rsync{},
+		TarEngine:    &Tar{},
+		RcloneEngine: &Rclone{},
+	}
+)
+
+// Endpoint is one side of a transfer. It is remote, i.e. reached over SSH, if it has an SSH host.
+type Endpoint struct {
+	Path    string
+	SSHHost string
+	SSHUser string
+	SSHPort int
+}
+
+func (e *Endpoint) remote() bool {
+	return e.SSHHost != ""
+}
+
+func (e *Endpoint) user() string {
+	if e.SSHUser != "" {
+		return e.SSHUser
+	}
+
+	return defaultSSHUser
+}
+
+// Options are the options of a transfer, independent of the engine running it.
+type Options struct {
+	NoChown         bool
+	Delete          bool
+	Compress        bool
+	SSHIdentityFile string
+	// SSHProxyCommand is the command to connect to the remote endpoint through, see ProxyCommand in ssh_config(5).
+	SSHProxyCommand string
+	SSHOptions      rsync.SSHOptions
+	Filter          rsync.Filter
+	Preserve        rsync.Preserve
+	LargeFile       rsync.LargeFile
+	// Ownership changes the owners of the files on the destination instead of preserving the ones of the source.
+	Ownership rsync.Ownership
+	// BwLimit is the maximum transfer rate in the syntax of the --bwlimit flag of rsync, e.g. 10M. Unlimited if empty.
+	BwLimit string
+	// AllowVanishedFiles treats the files vanishing from the source during the transfer as a success,
+	// e.g. for live sources.
+	AllowVanishedFiles bool
+	// ItemizeChanges outputs the changes of the files on the destination, to be parsed by progress.ParseChange.
+	ItemizeChanges bool
+	// Parallel is the number of concurrent streams to transfer the data with. A single stream is used if less than 2.
+	Parallel int
+	// ExtraArgs are additional arguments of rsync, given by the rsync.extraArgs helm value.
+	// Only supported by the rsync engine.
+	ExtraArgs []string
+}
+
+// ExitStatus is the meaning of an exit code of a transfer command.
+type ExitStatus struct {
+	Success   bool
+	Retryable bool
+	Reason    string
+	// Err is the kind of the failure, one of the Err* errors of this package. Nil if it is not known.
+	Err error
+	// Hint is the suggested action to fix the failure. Empty if there is none.
+	Hint string
+}
+
+// Engine is a tool which copies the data from one endpoint into another.
+type Engine interface {
+	// BuildCommand builds the shell command which copies the data from src into dest.
+	// At most one of the endpoints can be remote.
+	BuildCommand(src, dest *Endpoint, opts *Options) (string, error)
+
+	// ParseProgress parses a line in the output of the command into the progress of the transfer.
+	ParseProgress(line string) (progress.Progress, error)
+
+	// ClassifyExitCode returns the meaning of the exit code of the command.
+	// The output is the last lines of the output of the command, used to explain the failure further if not empty.
+	ClassifyExitCode(code int, output string) ExitStatus
+}
+
+// ParseProgressFunc returns the function parsing the progress from the output of the command of the engine,
+// combining the progress of the streams if the engine runs a process per stream.
+func ParseProgressFunc(engine Engine, parallel int) progress.ParseLineFunc {
+	if _, ok := engine.(*Rsync); ok && parallel > 1 {
+		return progress.NewCombined(parallel, engine.ParseProgress).ParseLine
+	}
+
+	return engine.ParseProgress
+}
+
+// Get returns the engine with the given name. An empty name returns the default engine.
+func Get(name string) (Engine, error) {
+	if name == "" {
+		name = DefaultEngine
+	}
+
+	engine, ok := nameToEngine[name]
+	if !ok {
+		return nil, fmt.Errorf("engine not found: %s", name)
+	}
+
+	return engine, nil
+}
+
+// sshFailureHint returns the hint of a failure to connect over ssh, based on the error of ssh in the output.
+func sshFailureHint(output string) string {
+	switch {
+	case strings.Contains(output, "Connection refused"):
+		return "SSH connection refused: check that the sshd pod is running and NetworkPolicies allow the traffic, " +
+			"or use --strategies local"
+	case strings.Contains(output, "timed out"), strings.Contains(output, "No route to host"):
+		return "SSH connection timed out: check NetworkPolicies and the firewalls between the clusters, " +
+			"or use --strategies local"
+	case strings.Contains(output, "Could not resolve hostname"):
+		return "SSH host could not be resolved: check the DNS of the cluster or --dest-host-override, " +
+			"or use --strategies local"
+	case strings.Contains(output, "Permission denied"):
+		return "SSH authentication failed: check that the sshd image supports the --ssh-key-algorithm"
+	default:
+		return "SSH connection failed: check NetworkPolicies or use --strategies local"
+	}
+}
@@ -0,0 +1,421 @@
+package transfer_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/utkuozdemir/pv-migrate/rsync"
+	"github.com/utkuozdemir/pv-migrate/rsync/progress"
+	"github.com/utkuozdemir/pv-migrate/transfer"
+)
+
+func TestGet(t *testing.T) {
+	t.Parallel()
+
+	engine, err := transfer.Get("")
+	require.NoError(t, err)
+	assert.IsType(t, &transfer.Rsync{}, engine)
+
+	_, err = transfer.Get("scp")
+	require.Error(t, err)
+}
+
+func TestRsyncBuildCommand(t *testing.T) {
+	t.Parallel()
+
+	src := transfer.Endpoint{Path: "/source/", SSHHost: "sshd.ns", SSHPort: 2222}
+	dest := transfer.Endpoint{Path: "/dest/"}
+
+	cmd, err := (&transfer.Rsync{}).BuildCommand(&src, &dest, &transfer.Options{NoChown: true, Delete: true})
+	require.NoError(t, err)
+	assert.Equal(t, "rsync -av --info=progress2,misc0,flist0 --stats --no-inc-recursive -e "+
+		"'ssh -o StrictHostKeyChecking=no -o UserKnownHostsFile=/dev/null -o ConnectTimeout=5 -p 2222' "+
+		"--no-o --no-g --delete root@sshd.ns:/source/ /dest/", cmd)
+}
+
+func TestRsyncBuildCommandProxyCommand(t *testing.T) {
+	t.Parallel()
+
+	src := transfer.Endpoint{Path: "/source/", SSHHost: "localhost", SSHPort: 30000}
+	dest := transfer.Endpoint{Path: "/dest/"}
+
+	cmd, err := (&transfer.Rsync{}).BuildCommand(&src, &dest,
+		&transfer.Options{SSHProxyCommand: "ssh -W %h:%p user@bastion"})
+	require.NoError(t, err)
+	assert.Equal(t, "rsync -av --info=progress2,misc0,flist0 --stats --no-inc-recursive -e "+
+		"\"ssh -o StrictHostKeyChecking=no -o UserKnownHostsFile=/dev/null -o ConnectTimeout=5 -p 30000 "+
+		"-o 'ProxyCommand=ssh -W %h:%p user@bastion'\" root@localhost:/source/ /dest/", cmd)
+
+	_, err = (&transfer.Rsync{}).BuildCommand(&src, &dest,
+		&transfer.Options{SSHProxyCommand: "sh -c 'nc %h %p'"})
+	require.Error(t, err)
+
+	_, err = (&transfer.Rclone{}).BuildCommand(&src, &dest,
+		&transfer.Options{SSHProxyCommand: "ssh -W %h:%p user@bastion"})
+	require.Error(t, err)
+}
+
+func TestBuildCommandSSHOptions(t *testing.T) {
+	t.Parallel()
+
+	src := transfer.Endpoint{Path: "/source/", SSHHost: "sshd"}
+	dest := transfer.Endpoint{Path: "/dest/"}
+	opts := transfer.Options{SSHOptions: rsync.SSHOptions{
+		ProxyJump:           []string{"jump@bastion:2222", "10.0.0.1"},
+		Ciphers:             []string{"aes128-gcm@openssh.com", "chacha20-poly1305@openssh.com"},
+		ConnectTimeout:      30 * time.Second,
+		ServerAliveInterval: 1500 * time.Millisecond,
+		ExtraOptions:        []string{"Compression=no", "LogLevel=ERROR"},
+	}}
+
+	cmd, err := (&transfer.Rsync{}).BuildCommand(&src, &dest, &opts)
+	require.NoError(t, err)
+	assert.Equal(t, "rsync -av --info=progress2,misc0,flist0 --stats --no-inc-recursive -e "+
+		"'ssh -o Compression=no -o LogLevel=ERROR -o StrictHostKeyChecking=no -o UserKnownHostsFile=/dev/null "+
+		"-o ConnectTimeout=30 -o ServerAliveInterval=2 -c aes128-gcm@openssh.com,chacha20-poly1305@openssh.com "+
+		"-J jump@bastion:2222,10.0.0.1' root@sshd:/source/ /dest/", cmd)
+
+	cmd, err = (&transfer.Tar{}).BuildCommand(&src, &dest, &opts)
+	require.NoError(t, err)
+	assert.Contains(t, cmd, "ssh -o Compression=no -o LogLevel=ERROR -o StrictHostKeyChecking=no "+
+		"-o UserKnownHostsFile=/dev/null -o ConnectTimeout=30 -o ServerAliveInterval=2 "+
+		"-c aes128-gcm@openssh.com,chacha20-poly1305@openssh.com -J jump@bastion:2222,10.0.0.1 -p 22 root@sshd")
+
+	_, err = (&transfer.Rclone{}).BuildCommand(&src, &dest, &opts)
+	require.Error(t, err)
+
+	opts.SSHProxyCommand = "ssh -W %h:%p user@bastion"
+
+	_, err = (&transfer.Rsync{}).BuildCommand(&src, &dest, &opts)
+	require.Error(t, err)
+
+	cmd, err = (&transfer.Rclone{}).BuildCommand(&src, &dest, &transfer.Options{SSHOptions: rsync.SSHOptions{
+		Ciphers: []string{"aes128-ctr"}, ConnectTimeout: time.Minute,
+	}})
+	require.NoError(t, err)
+	assert.Contains(t, cmd, "--sftp-ciphers aes128-ctr --contimeout 1m0s")
+}
+
+func TestBuildCommandFilter(t *testing.T) {
+	t.Parallel()
+
+	src := transfer.Endpoint{Path: "/source/"}
+	dest := transfer.Endpoint{Path: "/dest/"}
+	opts := transfer.Options{Filter: rsync.Filter{
+		Includes:  []string{"/cache/keep/"},
+		Excludes:  []string{"lost+found", "/cache/", "it's *.log"},
+		RulesFile: "/tmp/filter-rules",
+	}}
+
+	cmd, err := (&transfer.Rsync{}).BuildCommand(&src, &dest, &opts)
+	require.NoError(t, err)
+	assert.Contains(t, cmd, "--include=/cache/keep/ --exclude=lost+found --exclude=/cache/ "+
+		`"--exclude=it's *.log" '--filter=merge /tmp/filter-rules' /source/ /dest/`)
+
+	_, err = (&transfer.Tar{}).BuildCommand(&src, &dest, &opts)
+	require.Error(t, err)
+
+	_, err = (&transfer.Rclone{}).BuildCommand(&src, &dest, &opts)
+	require.Error(t, err)
+
+	excludesOnly := transfer.Options{Filter: rsync.Filter{Excludes: []string{"lost+found"}}}
+
+	cmd, err = (&transfer.Tar{}).BuildCommand(&src, &dest, &excludesOnly)
+	require.NoError(t, err)
+	assert.Contains(t, cmd, "tar -c -f - '--exclude=lost+found' -C '/source/' .")
+
+	cmd, err = (&transfer.Rclone{}).BuildCommand(&src, &dest, &excludesOnly)
+	require.NoError(t, err)
+	assert.Contains(t, cmd, "--exclude 'lost+found' --exclude 'lost+found/**'")
+
+	// rclone only matches the directory itself with the pattern of rsync, so its contents are excluded as well
+	excludesOnly.Filter.Excludes = []string{"/cache", "logs/", "tmp/***", "**.bak"}
+
+	cmd, err = (&transfer.Rclone{}).BuildCommand(&src, &dest, &excludesOnly)
+	require.NoError(t, err)
+	assert.Contains(t, cmd, "--exclude '/cache' --exclude '/cache/**' --exclude 'logs/**' --exclude 'tmp/**' "+
+		"--exclude '**.bak'")
+
+	_, err = (&transfer.Rsync{}).BuildCommand(&src, &dest,
+		&transfer.Options{Filter: rsync.Filter{Excludes: []string{"a\nb"}}})
+	require.Error(t, err)
+}
+
+func TestTarExcludeArgs(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, []string{"--exclude=lost+found", "--exclude=./cache", "--exclude=logs", "--exclude=./a/*.tmp"},
+		transfer.TarExcludeArgs([]string{"lost+found", "/cache/", "logs/", "/a/*.tmp"}))
+}
+
+func TestBuildCommandBwLimit(t *testing.T) {
+	t.Parallel()
+
+	src := transfer.Endpoint{Path: "/source/"}
+	dest := transfer.Endpoint{Path: "/dest/", SSHHost: "sshd"}
+	opts := transfer.Options{BwLimit: "10M"}
+
+	cmd, err := (&transfer.Rsync{}).BuildCommand(&src, &dest, &opts)
+	require.NoError(t, err)
+	assert.Contains(t, cmd, " --bwlimit=10M ")
+
+	cmd, err = (&transfer.Rclone{}).BuildCommand(&src, &dest, &opts)
+	require.NoError(t, err)
+	assert.Contains(t, cmd, " --bwlimit 10M")
+
+	_, err = (&transfer.Tar{}).BuildCommand(&src, &dest, &opts)
+	require.Error(t, err)
+
+	for _, invalid := range []string{"10MB/s", "-1", "10M; reboot", "fast"} {
+		_, err = (&transfer.Rsync{}).BuildCommand(&src, &dest, &transfer.Options{BwLimit: invalid})
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestBuildCommandPreserve(t *testing.T) {
+	t.Parallel()
+
+	src := transfer.Endpoint{Path: "/source/"}
+	dest := transfer.Endpoint{Path: "/dest/"}
+	opts := transfer.Options{Preserve: rsync.Preserve{Hardlinks: true, Sparse: true, NumericIDs: true}}
+
+	cmd, err := (&transfer.Rsync{}).BuildCommand(&src, &dest, &opts)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(cmd, "rsync_version=$(rsync --version) || exit $?; "), cmd)
+	assert.Contains(t, cmd, " -H -S --numeric-ids /source/ /dest/")
+
+	_, err = (&transfer.Tar{}).BuildCommand(&src, &dest, &opts)
+	require.Error(t, err)
+
+	_, err = (&transfer.Rclone{}).BuildCommand(&src, &dest, &opts)
+	require.Error(t, err)
+}
+
+func TestBuildCommandLargeFile(t *testing.T) {
+	t.Parallel()
+
+	src := transfer.Endpoint{Path: "/source/"}
+	dest := transfer.Endpoint{Path: "/dest/"}
+	opts := transfer.Options{LargeFile: rsync.LargeFile{Partial: true, Checksum: true}}
+
+	cmd, err := (&transfer.Rsync{}).BuildCommand(&src, &dest, &opts)
+	require.NoError(t, err)
+	assert.Contains(t, cmd, " --partial-dir="+rsync.PartialDir+" --checksum /source/ /dest/")
+
+	opts.LargeFile = rsync.LargeFile{Partial: true, Inplace: true}
+	_, err = (&transfer.Rsync{}).BuildCommand(&src, &dest, &opts)
+	require.Error(t, err)
+
+	opts.LargeFile = rsync.LargeFile{Inplace: true}
+	_, err = (&transfer.Tar{}).BuildCommand(&src, &dest, &opts)
+	require.Error(t, err)
+
+	_, err = (&transfer.Rclone{}).BuildCommand(&src, &dest, &opts)
+	require.Error(t, err)
+}
+
+func TestBuildCommandOwnership(t *testing.T) {
+	t.Parallel()
+
+	src := transfer.Endpoint{Path: "/source/"}
+	dest := transfer.Endpoint{Path: "/dest/"}
+	opts := transfer.Options{Ownership: rsync.Ownership{
+		UserMap:  []string{"1000:2000", "*:3000"},
+		GroupMap: []string{"0-99:0"},
+	}}
+
+	cmd, err := (&transfer.Rsync{}).BuildCommand(&src, &dest, &opts)
+	require.NoError(t, err)
+	assert.Contains(t, cmd, " '--usermap=1000:2000,*:3000' --groupmap=0-99:0 /source/ /dest/")
+
+	opts.NoChown = true
+	_, err = (&transfer.Rsync{}).BuildCommand(&src, &dest, &opts)
+	require.Error(t, err)
+
+	opts = transfer.Options{Ownership: rsync.Ownership{Chown: ":1000"}}
+	_, err = (&transfer.Tar{}).BuildCommand(&src, &dest, &opts)
+	require.Error(t, err)
+
+	_, err = (&transfer.Rclone{}).BuildCommand(&src, &dest, &opts)
+	require.Error(t, err)
+}
+
+func TestBuildCommandParallel(t *testing.T) {
+	t.Parallel()
+
+	src := transfer.Endpoint{Path: "/source/"}
+	dest := transfer.Endpoint{Path: "/dest/", SSHHost: "dest-host"}
+	opts := transfer.Options{Parallel: 4}
+
+	cmd, err := (&transfer.Rsync{}).BuildCommand(&src, &dest, &opts)
+	require.NoError(t, err)
+	assert.Contains(t, cmd, "awk -v n=4 ")
+	assert.Contains(t, cmd, `/source/ root@dest-host:/dest/ 2>&1 | `)
+
+	cmd, err = (&transfer.Rclone{}).BuildCommand(&src, &dest, &opts)
+	require.NoError(t, err)
+	assert.Contains(t, cmd, " --transfers 4 ")
+
+	_, err = (&transfer.Tar{}).BuildCommand(&src, &dest, &opts)
+	require.Error(t, err)
+}
+
+func TestParseProgressFunc(t *testing.T) {
+	t.Parallel()
+
+	parse := transfer.ParseProgressFunc(&transfer.Rsync{}, 2)
+
+	_, err := parse("total size is 10,000  speedup is 1.00")
+	require.Error(t, err)
+
+	p, err := parse("[stream 1] total size is 10,000  speedup is 1.00")
+	require.NoError(t, err)
+	assert.Equal(t, int64(10000), p.Transferred)
+
+	p, err = transfer.ParseProgressFunc(&transfer.Rsync{}, 1)("total size is 10,000  speedup is 1.00")
+	require.NoError(t, err)
+	assert.Equal(t, 100, p.Percentage)
+}
+
+func TestBuildCommandItemizeChanges(t *testing.T) {
+	t.Parallel()
+
+	src := transfer.Endpoint{Path: "/source/"}
+	dest := transfer.Endpoint{Path: "/dest/"}
+	opts := transfer.Options{ItemizeChanges: true}
+
+	cmd, err := (&transfer.Rsync{}).BuildCommand(&src, &dest, &opts)
+	require.NoError(t, err)
+	assert.Contains(t, cmd, " '--out-format="+progress.ChangeOutFormat+"' /source/ /dest/")
+
+	_, err = (&transfer.Tar{}).BuildCommand(&src, &dest, &opts)
+	require.Error(t, err)
+
+	_, err = (&transfer.Rclone{}).BuildCommand(&src, &dest, &opts)
+	require.Error(t, err)
+}
+
+func TestTarBuildCommand(t *testing.T) {
+	t.Parallel()
+
+	engine := transfer.Tar{}
+
+	cmd, err := engine.BuildCommand(&transfer.Endpoint{Path: "/source/"}, &transfer.Endpoint{Path: "/dest/"},
+		&transfer.Options{Compress: true})
+	require.NoError(t, err)
+	assert.Equal(t, "(set -o pipefail && tar -c -f - -C '/source/' . | "+
+		"(mkdir -p '/dest/' && tar -x -f - -C '/dest/'))", cmd)
+
+	cmd, err = engine.BuildCommand(&transfer.Endpoint{Path: "/source/"},
+		&transfer.Endpoint{Path: "/dest/my dir", SSHHost: "localhost", SSHPort: 50000},
+		&transfer.Options{Compress: true, NoChown: true, SSHIdentityFile: "/tmp/id_ed25519"})
+	require.NoError(t, err)
+	assert.Equal(t, "(set -o pipefail && tar -c -z -f - -C '/source/' . | "+
+		"ssh -o StrictHostKeyChecking=no -o UserKnownHostsFile=/dev/null -o ConnectTimeout=5 -p 50000 "+
+		"-i /tmp/id_ed25519 root@localhost "+
+		`'mkdir -p '\''/dest/my dir'\'' && tar -x -z -f - -C '\''/dest/my dir'\'' -o')`, cmd)
+
+	_, err = engine.BuildCommand(&transfer.Endpoint{Path: "/source/"}, &transfer.Endpoint{Path: "/dest/"},
+		&transfer.Options{Delete: true})
+	require.Error(t, err)
+}
+
+func TestRcloneBuildCommand(t *testing.T) {
+	t.Parallel()
+
+	src := transfer.Endpoint{Path: "/source/", SSHHost: "sshd.ns"}
+	dest := transfer.Endpoint{Path: "/dest/"}
+
+	cmd, err := (&transfer.Rclone{}).BuildCommand(&src, &dest,
+		&transfer.Options{Delete: true, SSHIdentityFile: "/tmp/id_ed25519"})
+	require.NoError(t, err)
+	assert.Equal(t, "rclone sync ':sftp:/source/' '/dest/' --use-json-log --stats 1s --stats-log-level NOTICE "+
+		"--sftp-host sshd.ns --sftp-port 22 --sftp-user root --sftp-key-file /tmp/id_ed25519", cmd)
+}
+
+func TestRcloneParseProgress(t *testing.T) {
+	t.Parallel()
+
+	engine := transfer.Rclone{}
+
+	prog, err := engine.ParseProgress(`{"level":"notice","msg":"...","stats":{"bytes":256,"totalBytes":1024}}`)
+	require.NoError(t, err)
+	assert.Equal(t, 25, prog.Percentage)
+	assert.Equal(t, int64(256), prog.Transferred)
+	assert.Equal(t, int64(1024), prog.Total)
+
+	prog, err = engine.ParseProgress(`{"level":"notice","msg":"...","stats":{"bytes":0,"totalBytes":0}}`)
+	require.NoError(t, err)
+	assert.Equal(t, 0, prog.Percentage)
+
+	_, err = engine.ParseProgress(`{"level":"info","msg":"Copied (new)"}`)
+	require.Error(t, err)
+
+	_, err = engine.ParseProgress("not json")
+	require.Error(t, err)
+}
+
+func TestClassifyExitCode(t *testing.T) {
+	t.Parallel()
+
+	assert.True(t, (&transfer.Rsync{}).ClassifyExitCode(0, "").Success)
+
+	status := (&transfer.Rsync{}).ClassifyExitCode(23, "")
+	assert.False(t, status.Success)
+	assert.False(t, status.Retryable)
+	assert.Equal(t, "partial transfer due to error", status.Reason)
+
+	assert.False(t, (&transfer.Rsync{}).ClassifyExitCode(1, "").Retryable)
+	assert.True(t, (&transfer.Rsync{}).ClassifyExitCode(12, "").Retryable)
+	assert.True(t, (&transfer.Tar{}).ClassifyExitCode(255, "").Retryable)
+	assert.True(t, (&transfer.Rclone{}).ClassifyExitCode(5, "").Retryable)
+	assert.Equal(t, "fatal error", (&transfer.Rclone{}).ClassifyExitCode(7, "").Reason)
+}
+
+func TestClassifyExitCodeHints(t *testing.T) {
+	t.Parallel()
+
+	for code, expected := range map[int]error{
+		12:  transfer.ErrProtocol,
+		23:  transfer.ErrPartialTransfer,
+		24:  transfer.ErrVanishedFiles,
+		255: transfer.ErrSSHConnection,
+	} {
+		status := (&transfer.Rsync{}).ClassifyExitCode(code, "")
+		assert.Equal(t, expected, status.Err, code)
+		assert.NotEmpty(t, status.Hint, code)
+	}
+
+	assert.NoError(t, (&transfer.Rsync{}).ClassifyExitCode(1, "").Err)
+
+	status := (&transfer.Rsync{}).ClassifyExitCode(255,
+		"ssh: connect to host 10.0.0.1 port 22: Connection refused\r\nrsync: connection unexpectedly closed")
+	assert.True(t, strings.HasPrefix(status.Hint, "SSH connection refused: "), status.Hint)
+
+	status = (&transfer.Tar{}).ClassifyExitCode(255, "ssh: connect to host 10.0.0.1 port 22: Connection timed out")
+	assert.Equal(t, transfer.ErrSSHConnection, status.Err)
+	assert.True(t, strings.HasPrefix(status.Hint, "SSH connection timed out: "), status.Hint)
+}
+
+func TestBuildCommandAllowVanishedFiles(t *testing.T) {
+	t.Parallel()
+
+	src := transfer.Endpoint{Path: "/source/"}
+	dest := transfer.Endpoint{Path: "/dest/"}
+	opts := transfer.Options{AllowVanishedFiles: true}
+
+	cmd, err := (&transfer.Rsync{}).BuildCommand(&src, &dest, &opts)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(cmd, "{ rsync -av "), cmd)
+	assert.Contains(t, cmd, `/source/ /dest/ || { code=$?; if [ "$code" -eq 24 ]; then `)
+
+	_, err = (&transfer.Tar{}).BuildCommand(&src, &dest, &opts)
+	require.Error(t, err)
+
+	_, err = (&transfer.Rclone{}).BuildCommand(&src, &dest, &opts)
+	require.Error(t, err)
+}
@@ -5,8 +5,12 @@ import (
 	"fmt"
 	"math/big"
 	"net"
+	"time"
 )
 
+// MaxBackoff is the maximum wait between the retries returned by Backoff.
+const MaxBackoff = 5 * time.Minute
+
 var letters = []rune("abcdefghijklmnopqrstuvwxyz0123456789")
 
 // RandomHexadecimalString returns a random lowercase hexadecimal string of given length.
@@ -34,3 +38,15 @@ func IsIPv6(host string) bool {
 
 	return ip.To4() == nil
 }
+
+// Backoff returns the wait before the given retry, starting from 1, doubling the initial backoff
+// on each retry up to MaxBackoff.
+func Backoff(initial time.Duration, retry int) time.Duration {
+	backoff := initial
+
+	for i := 1; i < retry && backoff < MaxBackoff; i++ {
+		backoff *= 2
+	}
+
+	return min(backoff, MaxBackoff)
+}
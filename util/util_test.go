@@ -2,6 +2,7 @@ package util
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -13,3 +14,13 @@ func TestIsIPv6(t *testing.T) {
 	assert.True(t, IsIPv6("2001:0db8:85a3:0000:0000:8a2e:0370:7334"))
 	assert.True(t, IsIPv6("::1"))
 }
+
+func TestBackoff(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, time.Minute, Backoff(time.Minute, 1))
+	assert.Equal(t, 2*time.Minute, Backoff(time.Minute, 2))
+	assert.Equal(t, 20*time.Second, Backoff(5*time.Second, 3))
+	assert.Equal(t, MaxBackoff, Backoff(time.Minute, 10))
+	assert.Equal(t, time.Duration(0), Backoff(0, 3))
+}